@@ -0,0 +1,71 @@
+package main
+
+import (
+    "context"
+    "crypto/subtle"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "strings"
+
+    "web3server/txmgr"
+
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/gin-gonic/gin"
+)
+
+// adminAuth guards the admin routes with the ADMIN_TOKEN bearer token. The
+// routes stay closed when no token is configured.
+func adminAuth(c *gin.Context) {
+    token := os.Getenv("ADMIN_TOKEN")
+    provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+    if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+        c.AbortWithStatusJSON(401, gin.H{"error": "Admin token required"})
+        return
+    }
+    c.Next()
+}
+
+// onTransactionReplaced keeps upload jobs pointed at the live transaction,
+// or ends them when the upload was cancelled
+func onTransactionReplaced(oldHash, newHash common.Hash, cancelled bool) {
+    if uploads == nil {
+        return
+    }
+    if err := uploads.Replace(oldHash, newHash, cancelled); err != nil {
+        log.Printf("Failed to update job for replaced transaction %s: %v", oldHash.Hex(), err)
+    }
+}
+
+func getPendingTransactions(c *gin.Context) {
+    c.JSON(200, transactions.Pending())
+}
+
+func speedUpTransaction(c *gin.Context) {
+    replaceTransaction(c, transactions.SpeedUp)
+}
+
+func cancelTransaction(c *gin.Context) {
+    replaceTransaction(c, transactions.Cancel)
+}
+
+func replaceTransaction(c *gin.Context, replace func(context.Context, common.Hash) (txmgr.Pending, error)) {
+    hash := c.Param("hash")
+    if len(strings.TrimPrefix(hash, "0x")) != 2*common.HashLength {
+        c.JSON(400, gin.H{"error": "Transaction hash must be 32 bytes of hex"})
+        return
+    }
+
+    pending, err := replace(c.Request.Context(), common.HexToHash(hash))
+    if errors.Is(err, txmgr.ErrTxNotTracked) {
+        c.JSON(404, gin.H{"error": "Transaction is not pending"})
+        return
+    }
+    if err != nil {
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to replace transaction: %v", err)})
+        return
+    }
+
+    c.JSON(200, pending)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "runtime"
+    "sync"
+    "time"
+
+    h "web3server/helper"
+    "web3server/txmgr"
+
+    "github.com/gin-gonic/gin"
+)
+
+// maxBatchSize bounds how many records one batch upload may carry
+const maxBatchSize = 500
+
+// batchItem is one record of a batch upload
+type batchItem struct {
+    Data        string `json:"data"`
+    Owner       string `json:"owner"`
+    DataName    string `json:"dataName"`
+    ReleaseTime uint64 `json:"releaseTime"`
+}
+
+// batchResult reports what happened to one record of a batch
+type batchResult struct {
+    Index           int    `json:"index"`
+    Owner           string `json:"owner"`
+    DataName        string `json:"dataName"`
+    Status          string `json:"status"`
+    JobID           string `json:"jobId,omitempty"`
+    TransactionHash string `json:"transactionHash,omitempty"`
+    KeyEscrowed     bool   `json:"keyEscrowed"`
+    Error           string `json:"error,omitempty"`
+}
+
+const (
+    batchSubmitted = "submitted"
+    batchFailed    = "failed"
+)
+
+// postDataBatch uploads a JSON array of records. Every item is validated
+// before anything is sent, so a bad item rejects the whole batch with 400.
+// Items are then sealed and estimated in parallel, and signed and sent back
+// to back with sequential nonces without waiting for each to be mined. Send failures only affect
+// their own item: the response is 202 when every item was submitted with
+// its key escrowed and 207 with per-item results otherwise. The cipher,
+// release, storage and gasStrategy options of /upload are taken from the
+// query string and apply to all items.
+func postDataBatch(c *gin.Context) {
+    var items []batchItem
+    if err := c.ShouldBindJSON(&items); err != nil {
+        c.JSON(400, gin.H{"error": fmt.Sprintf("Body must be a JSON array of records: %v", err)})
+        return
+    }
+    if len(items) == 0 {
+        c.JSON(400, gin.H{"error": "Batch cannot be empty"})
+        return
+    }
+    if len(items) > maxBatchSize {
+        c.JSON(400, gin.H{"error": fmt.Sprintf("Batch cannot hold more than %d records", maxBatchSize)})
+        return
+    }
+
+    cipherName := c.DefaultQuery("cipher", h.DefaultCipher().Name())
+    releaseMode := c.DefaultQuery("release", releaseModeKey)
+    storage := c.DefaultQuery("storage", storageChain)
+    if _, err := h.CipherByName(cipherName); err != nil {
+        c.JSON(400, gin.H{"error": err.Error()})
+        return
+    }
+    if releaseMode != releaseModeKey && releaseMode != releaseModePuzzle {
+        c.JSON(400, gin.H{"error": fmt.Sprintf("Unknown release mode %q", releaseMode)})
+        return
+    }
+    if releaseMode == releaseModePuzzle && squaringRate == 0 {
+        c.JSON(400, gin.H{"error": "Time-locked release is disabled on this server"})
+        return
+    }
+    if storage != storageChain && storage != storageBlob {
+        c.JSON(400, gin.H{"error": fmt.Sprintf("Unknown storage mode %q", storage)})
+        return
+    }
+
+    strategy := gasStrategy
+    if name := c.Query("gasStrategy"); name != "" {
+        var err error
+        strategy, err = txmgr.StrategyByName(name, gasMaxFeeCap)
+        if err != nil {
+            c.JSON(400, gin.H{"error": err.Error()})
+            return
+        }
+    }
+
+    if invalid := validateBatch(items); len(invalid) > 0 {
+        c.JSON(400, gin.H{
+            "error":   fmt.Sprintf("%d of %d records are invalid; nothing was submitted", len(invalid), len(items)),
+            "invalid": invalid,
+        })
+        return
+    }
+
+    ctx := context.Background()
+
+    // One price for the whole batch, so its transactions mine together
+    fees, err := strategy.Fees(ctx, client)
+    if err != nil {
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to price transaction: %v", err)})
+        return
+    }
+
+    sealed := make([]sealedUpload, len(items))
+    calls := make([]uploadCall, len(items))
+    results := make([]batchResult, len(items))
+    for i, item := range items {
+        results[i] = batchResult{Index: i, Owner: item.Owner, DataName: item.DataName, Status: batchFailed}
+    }
+
+    // Seal and estimate concurrently; each item only waits on its own RPCs
+    parallel(len(items), func(i int) {
+        item := items[i]
+        upload, err := sealData(item.Data, cipherName, releaseMode, item.ReleaseTime)
+        if err != nil {
+            results[i].Error = err.Error()
+            return
+        }
+        payload, blob, err := onChainPayload(storage, upload.EncryptedData)
+        if err != nil {
+            results[i].Error = err.Error()
+            return
+        }
+
+        sealed[i] = upload
+        calls[i] = uploadCall{
+            Owner:       item.Owner,
+            DataName:    item.DataName,
+            ReleaseTime: item.ReleaseTime,
+            Payload:     payload,
+            Blob:        blob,
+            Hash:        upload.Hash,
+            KeyEscrowed: true,
+        }
+        if err := calls[i].estimate(ctx, fees); err != nil {
+            results[i].Error = err.Error()
+        }
+    })
+
+    // Then sign and send back to back with sequential nonces
+    var submitted int
+    for i := range items {
+        if results[i].Error != "" {
+            continue
+        }
+        job, err := calls[i].send(ctx, fees)
+        if err != nil {
+            results[i].Error = err.Error()
+            continue
+        }
+        results[i].Status = batchSubmitted
+        results[i].JobID = job.ID
+        results[i].TransactionHash = job.TxHash
+        submitted++
+
+        job, err = escrowReleaseKey(job, sealed[i].PrivateKey)
+        if err != nil {
+            results[i].Error = err.Error()
+        }
+        results[i].KeyEscrowed = job.KeyEscrowed
+    }
+
+    status := 202
+    for _, result := range results {
+        if result.Error != "" {
+            status = 207
+        }
+    }
+    c.JSON(status, gin.H{
+        "message":   fmt.Sprintf("Submitted %d of %d records", submitted, len(items)),
+        "submitted": submitted,
+        "failed":    len(items) - submitted,
+        "fees":      fees,
+        "storage":   storage,
+        "results":   results,
+    })
+}
+
+// recordName identifies a record; a struct key keeps names containing a
+// separator from colliding
+type recordName struct {
+    Owner    string
+    DataName string
+}
+
+// validateBatch returns an entry for every item that cannot be uploaded,
+// including names repeated within the batch or already on chain
+func validateBatch(items []batchItem) []batchResult {
+    var invalid []batchResult
+    reject := func(i int, reason string) {
+        invalid = append(invalid, batchResult{
+            Index:    i,
+            Owner:    items[i].Owner,
+            DataName: items[i].DataName,
+            Status:   batchFailed,
+            Error:    reason,
+        })
+    }
+
+    now := uint64(time.Now().Unix())
+    seen := make(map[recordName]int, len(items))
+    for i, item := range items {
+        switch {
+        case item.Data == "":
+            reject(i, "Data cannot be empty")
+            continue
+        case item.Owner == "":
+            reject(i, "Owner cannot be empty")
+            continue
+        case item.DataName == "":
+            reject(i, "Data name cannot be empty")
+            continue
+        case item.ReleaseTime <= now:
+            reject(i, "Release time must be in the future")
+            continue
+        }
+
+        key := recordName{item.Owner, item.DataName}
+        if first, exists := seen[key]; exists {
+            reject(i, fmt.Sprintf("Duplicate of record %d", first))
+            continue
+        }
+        seen[key] = i
+
+        if _, exists, err := catalog.Get(item.Owner, item.DataName); err != nil {
+            reject(i, fmt.Sprintf("Failed to check for an existing record: %v", err))
+        } else if exists {
+            reject(i, "A record with this owner and data name already exists")
+        }
+    }
+    return invalid
+}
+
+// parallel calls fn for every index in [0, n) on up to one worker per CPU
+func parallel(n int, fn func(i int)) {
+    indexes := make(chan int)
+    var wg sync.WaitGroup
+    for w := 0; w < runtime.NumCPU() && w < n; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range indexes {
+                fn(i)
+            }
+        }()
+    }
+    for i := 0; i < n; i++ {
+        indexes <- i
+    }
+    close(indexes)
+    wg.Wait()
+}
@@ -0,0 +1,55 @@
+package bindings
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "strings"
+
+    "github.com/ethereum/go-ethereum/accounts/abi"
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/common/hexutil"
+    "github.com/ethereum/go-ethereum/core/types"
+)
+
+// Artifact is the part of a Hardhat artifact needed to deploy a contract
+type Artifact struct {
+    ContractName string          `json:"contractName"`
+    ABI          json.RawMessage `json:"abi"`
+    Bytecode     string          `json:"bytecode"`
+}
+
+// ReadArtifact loads a compiled Hardhat artifact from path
+func ReadArtifact(path string) (*Artifact, error) {
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read artifact: %w", err)
+    }
+
+    var a Artifact
+    if err := json.Unmarshal(raw, &a); err != nil {
+        return nil, fmt.Errorf("failed to parse artifact %s: %w", path, err)
+    }
+    if a.Bytecode == "" || a.Bytecode == "0x" {
+        return nil, errors.New("artifact has no bytecode; is the contract abstract?")
+    }
+    return &a, nil
+}
+
+// Deploy sends the artifact's creation bytecode and returns the address the
+// contract will live at once the transaction is mined
+func (a *Artifact) Deploy(opts *bind.TransactOpts, backend bind.ContractBackend) (common.Address, *types.Transaction, error) {
+    parsed, err := abi.JSON(strings.NewReader(string(a.ABI)))
+    if err != nil {
+        return common.Address{}, nil, fmt.Errorf("failed to parse artifact ABI: %w", err)
+    }
+    bytecode, err := hexutil.Decode(a.Bytecode)
+    if err != nil {
+        return common.Address{}, nil, fmt.Errorf("failed to decode artifact bytecode: %w", err)
+    }
+
+    address, tx, _, err := bind.DeployContract(opts, parsed, bytecode, backend)
+    return address, tx, err
+}
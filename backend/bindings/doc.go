@@ -0,0 +1,6 @@
+// Package bindings holds the generated Go bindings for the TwoPhaseCommit
+// contract. Do not edit twophasecommit.go by hand; compile the contract in
+// ../../hardhat and run `go generate ./bindings` instead.
+package bindings
+
+//go:generate go run ./gen -artifact ../../hardhat/artifacts/contracts/TwoPhaseCommit.sol/TwoPhaseCommit.json -out twophasecommit.go -copy ../TwoPhaseCommit.json
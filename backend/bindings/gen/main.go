@@ -0,0 +1,57 @@
+// Command gen regenerates the TwoPhaseCommit Go bindings from a compiled
+// Hardhat artifact. Run it through go generate in the bindings package
+// after `npx hardhat compile`.
+package main
+
+import (
+    "encoding/json"
+    "flag"
+    "log"
+    "os"
+
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+type artifact struct {
+    ContractName string          `json:"contractName"`
+    ABI          json.RawMessage `json:"abi"`
+    Bytecode     string          `json:"bytecode"`
+}
+
+func main() {
+    artifactPath := flag.String("artifact", "../../hardhat/artifacts/contracts/TwoPhaseCommit.sol/TwoPhaseCommit.json", "Hardhat artifact to bind")
+    out := flag.String("out", "twophasecommit.go", "file to write the bindings to")
+    pkg := flag.String("pkg", "bindings", "package name of the bindings")
+    copyTo := flag.String("copy", "", "also copy the artifact here, keeping the checked-in copy in sync")
+    flag.Parse()
+
+    raw, err := os.ReadFile(*artifactPath)
+    if err != nil {
+        log.Fatalf("Failed to read artifact: %v", err)
+    }
+
+    var a artifact
+    if err := json.Unmarshal(raw, &a); err != nil {
+        log.Fatalf("Failed to parse artifact: %v", err)
+    }
+
+    code, err := bind.Bind(
+        []string{a.ContractName},
+        []string{string(a.ABI)},
+        []string{a.Bytecode},
+        nil, *pkg, bind.LangGo, nil, nil,
+    )
+    if err != nil {
+        log.Fatalf("Failed to generate bindings: %v", err)
+    }
+
+    if err := os.WriteFile(*out, []byte(code), 0644); err != nil {
+        log.Fatalf("Failed to write bindings: %v", err)
+    }
+    if *copyTo != "" {
+        if err := os.WriteFile(*copyTo, raw, 0644); err != nil {
+            log.Fatalf("Failed to copy artifact: %v", err)
+        }
+    }
+    log.Printf("Generated %s bindings in %s", a.ContractName, *out)
+}
@@ -0,0 +1,34 @@
+package bindings
+
+import (
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/core/types"
+)
+
+// EstimateGas runs a typed transactor call without signing or sending it and
+// returns the gas limit go-ethereum estimated for it
+func EstimateGas(opts *bind.TransactOpts, call func(*bind.TransactOpts) (*types.Transaction, error)) (uint64, error) {
+    dryRun := *opts
+    dryRun.NoSend = true
+    dryRun.GasLimit = 0
+    dryRun.Signer = func(_ common.Address, tx *types.Transaction) (*types.Transaction, error) {
+        return tx, nil
+    }
+
+    tx, err := call(&dryRun)
+    if err != nil {
+        return 0, err
+    }
+    return tx.Gas(), nil
+}
+
+// EventID returns the topic of a TwoPhaseCommit event, e.g. "KeyReleased"
+func EventID(name string) common.Hash {
+    parsed, err := TwoPhaseCommitMetaData.GetAbi()
+    if err != nil {
+        // The ABI is generated and checked in, so this cannot fail at runtime
+        panic(err)
+    }
+    return parsed.Events[name].ID
+}
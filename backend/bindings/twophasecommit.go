@@ -0,0 +1,890 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package bindings
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// TwoPhaseCommitStoredData is an auto generated low-level Go binding around an user-defined struct.
+type TwoPhaseCommitStoredData struct {
+	EncryptedData []byte
+	Hash          []byte
+	Owner         string
+	DataName      string
+	ReleaseTime   *big.Int
+	KeyReleased   bool
+	Phase         *big.Int
+}
+
+// TwoPhaseCommitMetaData contains all meta data concerning the TwoPhaseCommit contract.
+var TwoPhaseCommitMetaData = &bind.MetaData{
+	ABI: "[{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"index\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"string\",\"name\":\"owner\",\"type\":\"string\"},{\"indexed\":false,\"internalType\":\"string\",\"name\":\"dataName\",\"type\":\"string\"}],\"name\":\"KeyReleaseRequested\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"bytes\",\"name\":\"privateKey\",\"type\":\"bytes\"},{\"indexed\":false,\"internalType\":\"string\",\"name\":\"owner\",\"type\":\"string\"},{\"indexed\":false,\"internalType\":\"string\",\"name\":\"dataName\",\"type\":\"string\"}],\"name\":\"KeyReleased\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"bytes\",\"name\":\"encryptedData\",\"type\":\"bytes\"},{\"indexed\":false,\"internalType\":\"string\",\"name\":\"owner\",\"type\":\"string\"},{\"indexed\":false,\"internalType\":\"string\",\"name\":\"dataName\",\"type\":\"string\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"releaseTime\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"bytes\",\"name\":\"hash\",\"type\":\"bytes\"}],\"name\":\"ReleaseEncryptedData\",\"type\":\"event\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"_dataName\",\"type\":\"string\"},{\"internalType\":\"string\",\"name\":\"_owner\",\"type\":\"string\"}],\"name\":\"GetPublicData\",\"outputs\":[{\"internalType\":\"bytes\",\"name\":\"\",\"type\":\"bytes\"},{\"internalType\":\"bytes\",\"name\":\"\",\"type\":\"bytes\"},{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"},{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"},{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"},{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes\",\"name\":\"_encryptedData\",\"type\":\"bytes\"},{\"internalType\":\"string\",\"name\":\"_owner\",\"type\":\"string\"},{\"internalType\":\"string\",\"name\":\"_dataName\",\"type\":\"string\"},{\"internalType\":\"uint256\",\"name\":\"_releaseTime\",\"type\":\"uint256\"},{\"internalType\":\"bytes\",\"name\":\"_hash\",\"type\":\"bytes\"}],\"name\":\"addStoredData\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes\",\"name\":\"\",\"type\":\"bytes\"}],\"name\":\"checkUpkeep\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"upkeepNeeded\",\"type\":\"bool\"},{\"internalType\":\"bytes\",\"name\":\"performData\",\"type\":\"bytes\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"clearStoredData\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes\",\"name\":\"\",\"type\":\"bytes\"}],\"name\":\"performUpkeep\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"_dataName\",\"type\":\"string\"},{\"internalType\":\"string\",\"name\":\"_owner\",\"type\":\"string\"},{\"internalType\":\"bytes\",\"name\":\"_privateKey\",\"type\":\"bytes\"}],\"name\":\"releaseKey\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"returnStoredData\",\"outputs\":[{\"components\":[{\"internalType\":\"bytes\",\"name\":\"encryptedData\",\"type\":\"bytes\"},{\"internalType\":\"bytes\",\"name\":\"hash\",\"type\":\"bytes\"},{\"internalType\":\"string\",\"name\":\"owner\",\"type\":\"string\"},{\"internalType\":\"string\",\"name\":\"dataName\",\"type\":\"string\"},{\"internalType\":\"uint256\",\"name\":\"releaseTime\",\"type\":\"uint256\"},{\"internalType\":\"bool\",\"name\":\"keyReleased\",\"type\":\"bool\"},{\"internalType\":\"int256\",\"name\":\"phase\",\"type\":\"int256\"}],\"internalType\":\"structTwoPhaseCommit.StoredData[]\",\"name\":\"\",\"type\":\"tuple[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"storedData\",\"outputs\":[{\"internalType\":\"bytes\",\"name\":\"encryptedData\",\"type\":\"bytes\"},{\"internalType\":\"bytes\",\"name\":\"hash\",\"type\":\"bytes\"},{\"internalType\":\"string\",\"name\":\"owner\",\"type\":\"string\"},{\"internalType\":\"string\",\"name\":\"dataName\",\"type\":\"string\"},{\"internalType\":\"uint256\",\"name\":\"releaseTime\",\"type\":\"uint256\"},{\"internalType\":\"bool\",\"name\":\"keyReleased\",\"type\":\"bool\"},{\"internalType\":\"int256\",\"name\":\"phase\",\"type\":\"int256\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]",
+	Bin: "0x608060405234801561001057600080fd5b50612b6d806100206000396000f3fe608060405234801561001057600080fd5b50600436106100885760003560e01c806378cb85261161005b57806378cb85261461011a578063a066b1181461014f578063b23231101461016b578063cdc642661461018957610088565b8063303771741461008d5780634079e7cf146100c35780634585e33b146100cd5780636e04ff0d146100e9575b600080fd5b6100a760048036038101906100a291906116a6565b6101a5565b6040516100ba97969594939291906117fb565b60405180910390f35b6100cb610424565b005b6100e760048036038101906100e291906118eb565b610433565b005b61010360048036038101906100fe91906118eb565b610746565b604051610111929190611938565b60405180910390f35b610134600480360381019061012f9190611a98565b61086a565b60405161014696959493929190611b10565b60405180910390f35b61016960048036038101906101649190611c2e565b610d93565b005b61017361103d565b6040516101809190611f4d565b60405180910390f35b6101a3600480360381019061019e9190611f6f565b611313565b005b600081815481106101b557600080fd5b90600052602060002090600702016000915090508060000180546101d890612045565b80601f016020809104026020016040519081016040528092919081815260200182805461020490612045565b80156102515780601f1061022657610100808354040283529160200191610251565b820191906000526020600020905b81548152906001019060200180831161023457829003601f168201915b50505050509080600101805461026690612045565b80601f016020809104026020016040519081016040528092919081815260200182805461029290612045565b80156102df5780601f106102b4576101008083540402835291602001916102df565b820191906000526020600020905b8154815290600101906020018083116102c257829003601f168201915b5050505050908060020180546102f490612045565b80601f016020809104026020016040519081016040528092919081815260200182805461032090612045565b801561036d5780601f106103425761010080835404028352916020019161036d565b820191906000526020600020905b81548152906001019060200180831161035057829003601f168201915b50505050509080600301805461038290612045565b80601f01602080910402602001604051908101604052809291908181526020018280546103ae90612045565b80156103fb5780601f106103d0576101008083540402835291602001916103fb565b820191906000526020600020905b8154815290600101906020018083116103de57829003601f168201915b5050505050908060040154908060050160009054906101000a900460ff16908060060154905087565b6000806104319190611520565b565b60005b60008054905081101561074157600080828154811061045857610457612076565b5b9060005260206000209060070201600601541480156104a957504261a8c06000838154811061048a57610489612076565b5b9060005260206000209060070201600401546104a691906120d4565b11155b156105d2576001600082815481106104c4576104c3612076565b5b9060005260206000209060070201600601819055507f06e562cca2e90b8faf389cce052e937c1d69c2eff30ff8d64a8b337e078649f76000828154811061050e5761050d612076565b5b90600052602060002090600702016000016000838154811061053357610532612076565b5b90600052602060002090600702016002016000848154811061055857610557612076565b5b90600052602060002090600702016003016000858154811061057d5761057c612076565b5b906000526020600020906007020160040154600086815481106105a3576105a2612076565b5b90600052602060002090600702016001016040516105c595949392919061223a565b60405180910390a161072e565b6001600082815481106105e8576105e7612076565b5b90600052602060002090600702016006015414801561062c5750426000828154811061061757610616612076565b5b90600052602060002090600702016004015411155b1561072d5760026000828154811061064757610646612076565b5b9060005260206000209060070201600601819055507f65869521f90f9b11b2ee3589b995705d524c73a765fa1840c915f48c159a5ac8600067ffffffffffffffff8111156106985761069761196d565b5b6040519080825280601f01601f1916602001820160405280156106ca5781602001600182028036833780820191505090505b50600083815481106106df576106de612076565b5b90600052602060002090600702016002016000848154811061070457610703612076565b5b9060005260206000209060070201600301604051610724939291906122a9565b60405180910390a15b5b8080610739906122f5565b915050610436565b505050565b600060606000915060005b60008054905081101561084d57600080828154811061077357610772612076565b5b9060005260206000209060070201600601541480156107c457504261a8c0600083815481106107a5576107a4612076565b5b9060005260206000209060070201600401546107c191906120d4565b11155b156107d2576001925061084d565b6001600082815481106107e8576107e7612076565b5b90600052602060002090600702016006015414801561082c5750426000828154811061081757610816612076565b5b90600052602060002090600702016004015411155b1561083a576001925061084d565b8080610845906122f5565b915050610751565b508160405180602001604052806000815250915091509250929050565b60608060608060008060005b600080549050811015610cb857886040516020016108949190612379565b60405160208183030381529060405280519060200120600082815481106108be576108bd612076565b5b90600052602060002090600702016003016040516020016108df9190612413565b6040516020818303038152906040528051906020012014801561097157508760405160200161090e9190612379565b604051602081830303815290604052805190602001206000828154811061093857610937612076565b5b90600052602060002090600702016002016040516020016109599190612413565b60405160208183030381529060405280519060200120145b15610ca5576000818154811061098a57610989612076565b5b9060005260206000209060070201600001600082815481106109af576109ae612076565b5b9060005260206000209060070201600101600083815481106109d4576109d3612076565b5b9060005260206000209060070201600201600084815481106109f9576109f8612076565b5b906000526020600020906007020160030160008581548110610a1e57610a1d612076565b5b90600052602060002090600702016004015460008681548110610a4457610a43612076565b5b906000526020600020906007020160050160009054906101000a900460ff16858054610a6f90612045565b80601f0160208091040260200160405190810160405280929190818152602001828054610a9b90612045565b8015610ae85780601f10610abd57610100808354040283529160200191610ae8565b820191906000526020600020905b815481529060010190602001808311610acb57829003601f168201915b50505050509550848054610afb90612045565b80601f0160208091040260200160405190810160405280929190818152602001828054610b2790612045565b8015610b745780601f10610b4957610100808354040283529160200191610b74565b820191906000526020600020905b815481529060010190602001808311610b5757829003601f168201915b50505050509450838054610b8790612045565b80601f0160208091040260200160405190810160405280929190818152602001828054610bb390612045565b8015610c005780601f10610bd557610100808354040283529160200191610c00565b820191906000526020600020905b815481529060010190602001808311610be357829003601f168201915b50505050509350828054610c1390612045565b80601f0160208091040260200160405190810160405280929190818152602001828054610c3f90612045565b8015610c8c5780601f10610c6157610100808354040283529160200191610c8c565b820191906000526020600020905b815481529060010190602001808311610c6f57829003601f168201915b5050505050925096509650965096509650965050610d89565b8080610cb0906122f5565b915050610876565b50600067ffffffffffffffff811115610cd457610cd361196d565b5b6040519080825280601f01601f191660200182016040528015610d065781602001600182028036833780820191505090505b50600067ffffffffffffffff811115610d2257610d2161196d565b5b6040519080825280601f01601f191660200182016040528015610d545781602001600182028036833780820191505090505b506000806040518060200160405280600081525091906040518060200160405280600081525091909550955095509550955095505b9295509295509295565b84848484846000855111610ddc576040517f08c379a0000000000000000000000000000000000000000000000000000000008152600401610dd390612476565b60405180910390fd5b6000845111610e20576040517f08c379a0000000000000000000000000000000000000000000000000000000008152600401610e17906124e2565b60405180910390fd5b6000835111610e64576040517f08c379a0000000000000000000000000000000000000000000000000000000008152600401610e5b9061254e565b60405180910390fd5b428211610ea6576040517f08c379a0000000000000000000000000000000000000000000000000000000008152600401610e9d906125e0565b60405180910390fd5b6000815111610eea576040517f08c379a0000000000000000000000000000000000000000000000000000000008152600401610ee19061264c565b60405180910390fd5b6000610ef6848661086a565b5094505050505060008114610f40576040517f08c379a0000000000000000000000000000000000000000000000000000000008152600401610f37906126de565b60405180910390fd5b60006040518060e001604052808d81526020018981526020018c81526020018b81526020018a8152602001600015158152602001600081525090806001815401808255809150506001900390600052602060002090600702016000909190919091506000820151816000019081610fb79190612895565b506020820151816001019081610fcd9190612895565b506040820151816002019081610fe391906129ad565b506060820151816003019081610ff991906129ad565b506080820151816004015560a08201518160050160006101000a81548160ff02191690831515021790555060c0820151816006015550505050505050505050505050565b60606000805480602002602001604051908101604052809291908181526020016000905b8282101561130a57838290600052602060002090600702016040518060e001604052908160008201805461109490612045565b80601f01602080910402602001604051908101604052809291908181526020018280546110c090612045565b801561110d5780601f106110e25761010080835404028352916020019161110d565b820191906000526020600020905b8154815290600101906020018083116110f057829003601f168201915b5050505050815260200160018201805461112690612045565b80601f016020809104026020016040519081016040528092919081815260200182805461115290612045565b801561119f5780601f106111745761010080835404028352916020019161119f565b820191906000526020600020905b81548152906001019060200180831161118257829003601f168201915b505050505081526020016002820180546111b890612045565b80601f01602080910402602001604051908101604052809291908181526020018280546111e490612045565b80156112315780601f1061120657610100808354040283529160200191611231565b820191906000526020600020905b81548152906001019060200180831161121457829003601f168201915b5050505050815260200160038201805461124a90612045565b80601f016020809104026020016040519081016040528092919081815260200182805461127690612045565b80156112c35780601f10611298576101008083540402835291602001916112c3565b820191906000526020600020905b8154815290600101906020018083116112a657829003601f168201915b50505050508152602001600482015481526020016005820160009054906101000a900460ff1615151515815260200160068201548152505081526020019060010190611061565b50505050905090565b60005b60008054905081101561151a57836040516020016113349190612379565b604051602081830303815290604052805190602001206000828154811061135e5761135d612076565b5b906000526020600020906007020160030160405160200161137f9190612413565b604051602081830303815290604052805190602001201480156114115750826040516020016113ae9190612379565b60405160208183030381529060405280519060200120600082815481106113d8576113d7612076565b5b90600052602060002090600702016002016040516020016113f99190612413565b60405160208183030381529060405280519060200120145b15611507576000818154811061142a57611429612076565b5b906000526020600020906007020160050160009054906101000a900460ff1615611489576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161148090612acb565b60405180910390fd5b60016000828154811061149f5761149e612076565b5b906000526020600020906007020160050160006101000a81548160ff0219169083151502179055507f65869521f90f9b11b2ee3589b995705d524c73a765fa1840c915f48c159a5ac88284866040516114fa93929190612aeb565b60405180910390a161151a565b8080611512906122f5565b915050611316565b50505050565b50805460008255600702906000526020600020908101906115419190611544565b50565b5b808211156115bb576000808201600061155e91906115bf565b60018201600061156e91906115bf565b60028201600061157e91906115ff565b60038201600061158e91906115ff565b60048201600090556005820160006101000a81549060ff0219169055600682016000905550600701611545565b5090565b5080546115cb90612045565b6000825580601f106115dd57506115fc565b601f0160209004906000526020600020908101906115fb919061163f565b5b50565b50805461160b90612045565b6000825580601f1061161d575061163c565b601f01602090049060005260206000209081019061163b919061163f565b5b50565b5b80821115611658576000816000905550600101611640565b5090565b6000604051905090565b600080fd5b600080fd5b6000819050919050565b61168381611670565b811461168e57600080fd5b50565b6000813590506116a08161167a565b92915050565b6000602082840312156116bc576116bb611666565b5b60006116ca84828501611691565b91505092915050565b600081519050919050565b600082825260208201905092915050565b60005b8381101561170d5780820151818401526020810190506116f2565b60008484015250505050565b6000601f19601f8301169050919050565b6000611735826116d3565b61173f81856116de565b935061174f8185602086016116ef565b61175881611719565b840191505092915050565b600081519050919050565b600082825260208201905092915050565b600061178a82611763565b611794818561176e565b93506117a48185602086016116ef565b6117ad81611719565b840191505092915050565b6117c181611670565b82525050565b60008115159050919050565b6117dc816117c7565b82525050565b6000819050919050565b6117f5816117e2565b82525050565b600060e0820190508181036000830152611815818a61172a565b90508181036020830152611829818961172a565b9050818103604083015261183d818861177f565b90508181036060830152611851818761177f565b905061186060808301866117b8565b61186d60a08301856117d3565b61187a60c08301846117ec565b98975050505050505050565b600080fd5b600080fd5b600080fd5b60008083601f8401126118ab576118aa611886565b5b8235905067ffffffffffffffff8111156118c8576118c761188b565b5b6020830191508360018202830111156118e4576118e3611890565b5b9250929050565b6000806020838503121561190257611901611666565b5b600083013567ffffffffffffffff8111156119205761191f61166b565b5b61192c85828601611895565b92509250509250929050565b600060408201905061194d60008301856117d3565b818103602083015261195f818461172a565b90509392505050565b600080fd5b7f4e487b7100000000000000000000000000000000000000000000000000000000600052604160045260246000fd5b6119a582611719565b810181811067ffffffffffffffff821117156119c4576119c361196d565b5b80604052505050565b60006119d761165c565b90506119e3828261199c565b919050565b600067ffffffffffffffff821115611a0357611a0261196d565b5b611a0c82611719565b9050602081019050919050565b82818337600083830152505050565b6000611a3b611a36846119e8565b6119cd565b905082815260208101848484011115611a5757611a56611968565b5b611a62848285611a19565b509392505050565b600082601f830112611a7f57611a7e611886565b5b8135611a8f848260208601611a28565b91505092915050565b60008060408385031215611aaf57611aae611666565b5b600083013567ffffffffffffffff811115611acd57611acc61166b565b5b611ad985828601611a6a565b925050602083013567ffffffffffffffff811115611afa57611af961166b565b5b611b0685828601611a6a565b9150509250929050565b600060c0820190508181036000830152611b2a818961172a565b90508181036020830152611b3e818861172a565b90508181036040830152611b52818761177f565b90508181036060830152611b66818661177f565b9050611b7560808301856117b8565b611b8260a08301846117d3565b979650505050505050565b600067ffffffffffffffff821115611ba857611ba761196d565b5b611bb182611719565b9050602081019050919050565b6000611bd1611bcc84611b8d565b6119cd565b905082815260208101848484011115611bed57611bec611968565b5b611bf8848285611a19565b509392505050565b600082601f830112611c1557611c14611886565b5b8135611c25848260208601611bbe565b91505092915050565b600080600080600060a08688031215611c4a57611c49611666565b5b600086013567ffffffffffffffff811115611c6857611c6761166b565b5b611c7488828901611c00565b955050602086013567ffffffffffffffff811115611c9557611c9461166b565b5b611ca188828901611a6a565b945050604086013567ffffffffffffffff811115611cc257611cc161166b565b5b611cce88828901611a6a565b9350506060611cdf88828901611691565b925050608086013567ffffffffffffffff811115611d0057611cff61166b565b5b611d0c88828901611c00565b9150509295509295909350565b600081519050919050565b600082825260208201905092915050565b6000819050602082019050919050565b600082825260208201905092915050565b6000611d61826116d3565b611d6b8185611d45565b9350611d7b8185602086016116ef565b611d8481611719565b840191505092915050565b600082825260208201905092915050565b6000611dab82611763565b611db58185611d8f565b9350611dc58185602086016116ef565b611dce81611719565b840191505092915050565b611de281611670565b82525050565b611df1816117c7565b82525050565b611e00816117e2565b82525050565b600060e0830160008301518482036000860152611e238282611d56565b91505060208301518482036020860152611e3d8282611d56565b91505060408301518482036040860152611e578282611da0565b91505060608301518482036060860152611e718282611da0565b9150506080830151611e866080860182611dd9565b5060a0830151611e9960a0860182611de8565b5060c0830151611eac60c0860182611df7565b508091505092915050565b6000611ec38383611e06565b905092915050565b6000602082019050919050565b6000611ee382611d19565b611eed8185611d24565b935083602082028501611eff85611d35565b8060005b85811015611f3b5784840389528151611f1c8582611eb7565b9450611f2783611ecb565b925060208a01995050600181019050611f03565b50829750879550505050505092915050565b60006020820190508181036000830152611f678184611ed8565b905092915050565b600080600060608486031215611f8857611f87611666565b5b600084013567ffffffffffffffff811115611fa657611fa561166b565b5b611fb286828701611a6a565b935050602084013567ffffffffffffffff811115611fd357611fd261166b565b5b611fdf86828701611a6a565b925050604084013567ffffffffffffffff81111561200057611fff61166b565b5b61200c86828701611c00565b9150509250925092565b7f4e487b7100000000000000000000000000000000000000000000000000000000600052602260045260246000fd5b6000600282049050600182168061205d57607f821691505b6020821081036120705761206f612016565b5b50919050565b7f4e487b7100000000000000000000000000000000000000000000000000000000600052603260045260246000fd5b7f4e487b7100000000000000000000000000000000000000000000000000000000600052601160045260246000fd5b60006120df82611670565b91506120ea83611670565b9250828203905081811115612102576121016120a5565b5b92915050565b60008190508160005260206000209050919050565b6000815461212a81612045565b61213481866116de565b9450600182166000811461214f576001811461216557612198565b60ff198316865281151560200286019350612198565b61216e85612108565b60005b8381101561219057815481890152600182019150602081019050612171565b808801955050505b50505092915050565b60008190508160005260206000209050919050565b600081546121c381612045565b6121cd818661176e565b945060018216600081146121e857600181146121fe57612231565b60ff198316865281151560200286019350612231565b612207856121a1565b60005b838110156122295781548189015260018201915060208101905061220a565b808801955050505b50505092915050565b600060a0820190508181036000830152612254818861211d565b9050818103602083015261226881876121b6565b9050818103604083015261227c81866121b6565b905061228b60608301856117b8565b818103608083015261229d818461211d565b90509695505050505050565b600060608201905081810360008301526122c3818661172a565b905081810360208301526122d781856121b6565b905081810360408301526122eb81846121b6565b9050949350505050565b600061230082611670565b91507fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff8203612332576123316120a5565b5b600182019050919050565b600081905092915050565b600061235382611763565b61235d818561233d565b935061236d8185602086016116ef565b80840191505092915050565b60006123858284612348565b915081905092915050565b6000815461239d81612045565b6123a7818661233d565b945060018216600081146123c257600181146123d75761240a565b60ff198316865281151582028601935061240a565b6123e0856121a1565b60005b83811015612402578154818901526001820191506020810190506123e3565b838801955050505b50505092915050565b600061241f8284612390565b915081905092915050565b7f456e637279707465642064617461206973207265717569726564000000000000600082015250565b6000612460601a8361176e565b915061246b8261242a565b602082019050919050565b6000602082019050818103600083015261248f81612453565b9050919050565b7f4f776e6572206973207265717569726564000000000000000000000000000000600082015250565b60006124cc60118361176e565b91506124d782612496565b602082019050919050565b600060208201905081810360008301526124fb816124bf565b9050919050565b7f44617461206e616d652069732072657175697265640000000000000000000000600082015250565b600061253860158361176e565b915061254382612502565b602082019050919050565b600060208201905081810360008301526125678161252b565b9050919050565b7f52656c656173652074696d65206d75737420626520696e20746865206675747560008201527f7265000000000000000000000000000000000000000000000000000000000000602082015250565b60006125ca60228361176e565b91506125d58261256e565b604082019050919050565b600060208201905081810360008301526125f9816125bd565b9050919050565b7f4861736820697320726571756972656400000000000000000000000000000000600082015250565b600061263660108361176e565b915061264182612600565b602082019050919050565b6000602082019050818103600083015261266581612629565b9050919050565b7f44617461206e616d6520616e64206f776e657220636f6d62696e6174696f6e2060008201527f616c726561647920657869737473000000000000000000000000000000000000602082015250565b60006126c8602e8361176e565b91506126d38261266c565b604082019050919050565b600060208201905081810360008301526126f7816126bb565b9050919050565b60006020601f8301049050919050565b600082821b905092915050565b60006008830261274b7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff8261270e565b612755868361270e565b95508019841693508086168417925050509392505050565b6000819050919050565b600061279261278d61278884611670565b61276d565b611670565b9050919050565b6000819050919050565b6127ac83612777565b6127c06127b882612799565b84845461271b565b825550505050565b600090565b6127d56127c8565b6127e08184846127a3565b505050565b5b81811015612804576127f96000826127cd565b6001810190506127e6565b5050565b601f8211156128495761281a81612108565b612823846126fe565b81016020851015612832578190505b61284661283e856126fe565b8301826127e5565b50505b505050565b600082821c905092915050565b600061286c6000198460080261284e565b1980831691505092915050565b6000612885838361285b565b9150826002028217905092915050565b61289e826116d3565b67ffffffffffffffff8111156128b7576128b661196d565b5b6128c18254612045565b6128cc828285612808565b600060209050601f8311600181146128ff57600084156128ed578287015190505b6128f78582612879565b86555061295f565b601f19841661290d86612108565b60005b8281101561293557848901518255600182019150602085019450602081019050612910565b86831015612952578489015161294e601f89168261285b565b8355505b6001600288020188555050505b505050505050565b601f8211156129a857612979816121a1565b612982846126fe565b81016020851015612991578190505b6129a561299d856126fe565b8301826127e5565b50505b505050565b6129b682611763565b67ffffffffffffffff8111156129cf576129ce61196d565b5b6129d98254612045565b6129e4828285612967565b600060209050601f831160018114612a175760008415612a05578287015190505b612a0f8582612879565b865550612a77565b601f198416612a25866121a1565b60005b82811015612a4d57848901518255600182019150602085019450602081019050612a28565b86831015612a6a5784890151612a66601f89168261285b565b8355505b6001600288020188555050505b505050505050565b7f4b657920616c72656164792072656c6561736564000000000000000000000000600082015250565b6000612ab560148361176e565b9150612ac082612a7f565b602082019050919050565b60006020820190508181036000830152612ae481612aa8565b9050919050565b60006060820190508181036000830152612b05818661172a565b90508181036020830152612b19818561177f565b90508181036040830152612b2d818461177f565b905094935050505056fea2646970667358221220d15d043b446ac99c0c4f716617f5b390ffb60cbb92b77fd849b01559241e3c2564736f6c63430008130033",
+}
+
+// TwoPhaseCommitABI is the input ABI used to generate the binding from.
+// Deprecated: Use TwoPhaseCommitMetaData.ABI instead.
+var TwoPhaseCommitABI = TwoPhaseCommitMetaData.ABI
+
+// TwoPhaseCommitBin is the compiled bytecode used for deploying new contracts.
+// Deprecated: Use TwoPhaseCommitMetaData.Bin instead.
+var TwoPhaseCommitBin = TwoPhaseCommitMetaData.Bin
+
+// DeployTwoPhaseCommit deploys a new Ethereum contract, binding an instance of TwoPhaseCommit to it.
+func DeployTwoPhaseCommit(auth *bind.TransactOpts, backend bind.ContractBackend) (common.Address, *types.Transaction, *TwoPhaseCommit, error) {
+	parsed, err := TwoPhaseCommitMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	if parsed == nil {
+		return common.Address{}, nil, nil, errors.New("GetABI returned nil")
+	}
+
+	address, tx, contract, err := bind.DeployContract(auth, *parsed, common.FromHex(TwoPhaseCommitBin), backend)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, &TwoPhaseCommit{TwoPhaseCommitCaller: TwoPhaseCommitCaller{contract: contract}, TwoPhaseCommitTransactor: TwoPhaseCommitTransactor{contract: contract}, TwoPhaseCommitFilterer: TwoPhaseCommitFilterer{contract: contract}}, nil
+}
+
+// TwoPhaseCommit is an auto generated Go binding around an Ethereum contract.
+type TwoPhaseCommit struct {
+	TwoPhaseCommitCaller     // Read-only binding to the contract
+	TwoPhaseCommitTransactor // Write-only binding to the contract
+	TwoPhaseCommitFilterer   // Log filterer for contract events
+}
+
+// TwoPhaseCommitCaller is an auto generated read-only Go binding around an Ethereum contract.
+type TwoPhaseCommitCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// TwoPhaseCommitTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type TwoPhaseCommitTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// TwoPhaseCommitFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type TwoPhaseCommitFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// TwoPhaseCommitSession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type TwoPhaseCommitSession struct {
+	Contract     *TwoPhaseCommit   // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// TwoPhaseCommitCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type TwoPhaseCommitCallerSession struct {
+	Contract *TwoPhaseCommitCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts         // Call options to use throughout this session
+}
+
+// TwoPhaseCommitTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type TwoPhaseCommitTransactorSession struct {
+	Contract     *TwoPhaseCommitTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts         // Transaction auth options to use throughout this session
+}
+
+// TwoPhaseCommitRaw is an auto generated low-level Go binding around an Ethereum contract.
+type TwoPhaseCommitRaw struct {
+	Contract *TwoPhaseCommit // Generic contract binding to access the raw methods on
+}
+
+// TwoPhaseCommitCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type TwoPhaseCommitCallerRaw struct {
+	Contract *TwoPhaseCommitCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// TwoPhaseCommitTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type TwoPhaseCommitTransactorRaw struct {
+	Contract *TwoPhaseCommitTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewTwoPhaseCommit creates a new instance of TwoPhaseCommit, bound to a specific deployed contract.
+func NewTwoPhaseCommit(address common.Address, backend bind.ContractBackend) (*TwoPhaseCommit, error) {
+	contract, err := bindTwoPhaseCommit(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoPhaseCommit{TwoPhaseCommitCaller: TwoPhaseCommitCaller{contract: contract}, TwoPhaseCommitTransactor: TwoPhaseCommitTransactor{contract: contract}, TwoPhaseCommitFilterer: TwoPhaseCommitFilterer{contract: contract}}, nil
+}
+
+// NewTwoPhaseCommitCaller creates a new read-only instance of TwoPhaseCommit, bound to a specific deployed contract.
+func NewTwoPhaseCommitCaller(address common.Address, caller bind.ContractCaller) (*TwoPhaseCommitCaller, error) {
+	contract, err := bindTwoPhaseCommit(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoPhaseCommitCaller{contract: contract}, nil
+}
+
+// NewTwoPhaseCommitTransactor creates a new write-only instance of TwoPhaseCommit, bound to a specific deployed contract.
+func NewTwoPhaseCommitTransactor(address common.Address, transactor bind.ContractTransactor) (*TwoPhaseCommitTransactor, error) {
+	contract, err := bindTwoPhaseCommit(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoPhaseCommitTransactor{contract: contract}, nil
+}
+
+// NewTwoPhaseCommitFilterer creates a new log filterer instance of TwoPhaseCommit, bound to a specific deployed contract.
+func NewTwoPhaseCommitFilterer(address common.Address, filterer bind.ContractFilterer) (*TwoPhaseCommitFilterer, error) {
+	contract, err := bindTwoPhaseCommit(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoPhaseCommitFilterer{contract: contract}, nil
+}
+
+// bindTwoPhaseCommit binds a generic wrapper to an already deployed contract.
+func bindTwoPhaseCommit(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := TwoPhaseCommitMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_TwoPhaseCommit *TwoPhaseCommitRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _TwoPhaseCommit.Contract.TwoPhaseCommitCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_TwoPhaseCommit *TwoPhaseCommitRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.TwoPhaseCommitTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_TwoPhaseCommit *TwoPhaseCommitRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.TwoPhaseCommitTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_TwoPhaseCommit *TwoPhaseCommitCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _TwoPhaseCommit.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_TwoPhaseCommit *TwoPhaseCommitTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_TwoPhaseCommit *TwoPhaseCommitTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.contract.Transact(opts, method, params...)
+}
+
+// GetPublicData is a free data retrieval call binding the contract method 0x78cb8526.
+//
+// Solidity: function GetPublicData(string _dataName, string _owner) view returns(bytes, bytes, string, string, uint256, bool)
+func (_TwoPhaseCommit *TwoPhaseCommitCaller) GetPublicData(opts *bind.CallOpts, _dataName string, _owner string) ([]byte, []byte, string, string, *big.Int, bool, error) {
+	var out []interface{}
+	err := _TwoPhaseCommit.contract.Call(opts, &out, "GetPublicData", _dataName, _owner)
+
+	if err != nil {
+		return *new([]byte), *new([]byte), *new(string), *new(string), *new(*big.Int), *new(bool), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([]byte)).(*[]byte)
+	out1 := *abi.ConvertType(out[1], new([]byte)).(*[]byte)
+	out2 := *abi.ConvertType(out[2], new(string)).(*string)
+	out3 := *abi.ConvertType(out[3], new(string)).(*string)
+	out4 := *abi.ConvertType(out[4], new(*big.Int)).(**big.Int)
+	out5 := *abi.ConvertType(out[5], new(bool)).(*bool)
+
+	return out0, out1, out2, out3, out4, out5, err
+
+}
+
+// GetPublicData is a free data retrieval call binding the contract method 0x78cb8526.
+//
+// Solidity: function GetPublicData(string _dataName, string _owner) view returns(bytes, bytes, string, string, uint256, bool)
+func (_TwoPhaseCommit *TwoPhaseCommitSession) GetPublicData(_dataName string, _owner string) ([]byte, []byte, string, string, *big.Int, bool, error) {
+	return _TwoPhaseCommit.Contract.GetPublicData(&_TwoPhaseCommit.CallOpts, _dataName, _owner)
+}
+
+// GetPublicData is a free data retrieval call binding the contract method 0x78cb8526.
+//
+// Solidity: function GetPublicData(string _dataName, string _owner) view returns(bytes, bytes, string, string, uint256, bool)
+func (_TwoPhaseCommit *TwoPhaseCommitCallerSession) GetPublicData(_dataName string, _owner string) ([]byte, []byte, string, string, *big.Int, bool, error) {
+	return _TwoPhaseCommit.Contract.GetPublicData(&_TwoPhaseCommit.CallOpts, _dataName, _owner)
+}
+
+// CheckUpkeep is a free data retrieval call binding the contract method 0x6e04ff0d.
+//
+// Solidity: function checkUpkeep(bytes ) view returns(bool upkeepNeeded, bytes performData)
+func (_TwoPhaseCommit *TwoPhaseCommitCaller) CheckUpkeep(opts *bind.CallOpts, arg0 []byte) (struct {
+	UpkeepNeeded bool
+	PerformData  []byte
+}, error) {
+	var out []interface{}
+	err := _TwoPhaseCommit.contract.Call(opts, &out, "checkUpkeep", arg0)
+
+	outstruct := new(struct {
+		UpkeepNeeded bool
+		PerformData  []byte
+	})
+	if err != nil {
+		return *outstruct, err
+	}
+
+	outstruct.UpkeepNeeded = *abi.ConvertType(out[0], new(bool)).(*bool)
+	outstruct.PerformData = *abi.ConvertType(out[1], new([]byte)).(*[]byte)
+
+	return *outstruct, err
+
+}
+
+// CheckUpkeep is a free data retrieval call binding the contract method 0x6e04ff0d.
+//
+// Solidity: function checkUpkeep(bytes ) view returns(bool upkeepNeeded, bytes performData)
+func (_TwoPhaseCommit *TwoPhaseCommitSession) CheckUpkeep(arg0 []byte) (struct {
+	UpkeepNeeded bool
+	PerformData  []byte
+}, error) {
+	return _TwoPhaseCommit.Contract.CheckUpkeep(&_TwoPhaseCommit.CallOpts, arg0)
+}
+
+// CheckUpkeep is a free data retrieval call binding the contract method 0x6e04ff0d.
+//
+// Solidity: function checkUpkeep(bytes ) view returns(bool upkeepNeeded, bytes performData)
+func (_TwoPhaseCommit *TwoPhaseCommitCallerSession) CheckUpkeep(arg0 []byte) (struct {
+	UpkeepNeeded bool
+	PerformData  []byte
+}, error) {
+	return _TwoPhaseCommit.Contract.CheckUpkeep(&_TwoPhaseCommit.CallOpts, arg0)
+}
+
+// ReturnStoredData is a free data retrieval call binding the contract method 0xb2323110.
+//
+// Solidity: function returnStoredData() view returns((bytes,bytes,string,string,uint256,bool,int256)[])
+func (_TwoPhaseCommit *TwoPhaseCommitCaller) ReturnStoredData(opts *bind.CallOpts) ([]TwoPhaseCommitStoredData, error) {
+	var out []interface{}
+	err := _TwoPhaseCommit.contract.Call(opts, &out, "returnStoredData")
+
+	if err != nil {
+		return *new([]TwoPhaseCommitStoredData), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([]TwoPhaseCommitStoredData)).(*[]TwoPhaseCommitStoredData)
+
+	return out0, err
+
+}
+
+// ReturnStoredData is a free data retrieval call binding the contract method 0xb2323110.
+//
+// Solidity: function returnStoredData() view returns((bytes,bytes,string,string,uint256,bool,int256)[])
+func (_TwoPhaseCommit *TwoPhaseCommitSession) ReturnStoredData() ([]TwoPhaseCommitStoredData, error) {
+	return _TwoPhaseCommit.Contract.ReturnStoredData(&_TwoPhaseCommit.CallOpts)
+}
+
+// ReturnStoredData is a free data retrieval call binding the contract method 0xb2323110.
+//
+// Solidity: function returnStoredData() view returns((bytes,bytes,string,string,uint256,bool,int256)[])
+func (_TwoPhaseCommit *TwoPhaseCommitCallerSession) ReturnStoredData() ([]TwoPhaseCommitStoredData, error) {
+	return _TwoPhaseCommit.Contract.ReturnStoredData(&_TwoPhaseCommit.CallOpts)
+}
+
+// StoredData is a free data retrieval call binding the contract method 0x30377174.
+//
+// Solidity: function storedData(uint256 ) view returns(bytes encryptedData, bytes hash, string owner, string dataName, uint256 releaseTime, bool keyReleased, int256 phase)
+func (_TwoPhaseCommit *TwoPhaseCommitCaller) StoredData(opts *bind.CallOpts, arg0 *big.Int) (struct {
+	EncryptedData []byte
+	Hash          []byte
+	Owner         string
+	DataName      string
+	ReleaseTime   *big.Int
+	KeyReleased   bool
+	Phase         *big.Int
+}, error) {
+	var out []interface{}
+	err := _TwoPhaseCommit.contract.Call(opts, &out, "storedData", arg0)
+
+	outstruct := new(struct {
+		EncryptedData []byte
+		Hash          []byte
+		Owner         string
+		DataName      string
+		ReleaseTime   *big.Int
+		KeyReleased   bool
+		Phase         *big.Int
+	})
+	if err != nil {
+		return *outstruct, err
+	}
+
+	outstruct.EncryptedData = *abi.ConvertType(out[0], new([]byte)).(*[]byte)
+	outstruct.Hash = *abi.ConvertType(out[1], new([]byte)).(*[]byte)
+	outstruct.Owner = *abi.ConvertType(out[2], new(string)).(*string)
+	outstruct.DataName = *abi.ConvertType(out[3], new(string)).(*string)
+	outstruct.ReleaseTime = *abi.ConvertType(out[4], new(*big.Int)).(**big.Int)
+	outstruct.KeyReleased = *abi.ConvertType(out[5], new(bool)).(*bool)
+	outstruct.Phase = *abi.ConvertType(out[6], new(*big.Int)).(**big.Int)
+
+	return *outstruct, err
+
+}
+
+// StoredData is a free data retrieval call binding the contract method 0x30377174.
+//
+// Solidity: function storedData(uint256 ) view returns(bytes encryptedData, bytes hash, string owner, string dataName, uint256 releaseTime, bool keyReleased, int256 phase)
+func (_TwoPhaseCommit *TwoPhaseCommitSession) StoredData(arg0 *big.Int) (struct {
+	EncryptedData []byte
+	Hash          []byte
+	Owner         string
+	DataName      string
+	ReleaseTime   *big.Int
+	KeyReleased   bool
+	Phase         *big.Int
+}, error) {
+	return _TwoPhaseCommit.Contract.StoredData(&_TwoPhaseCommit.CallOpts, arg0)
+}
+
+// StoredData is a free data retrieval call binding the contract method 0x30377174.
+//
+// Solidity: function storedData(uint256 ) view returns(bytes encryptedData, bytes hash, string owner, string dataName, uint256 releaseTime, bool keyReleased, int256 phase)
+func (_TwoPhaseCommit *TwoPhaseCommitCallerSession) StoredData(arg0 *big.Int) (struct {
+	EncryptedData []byte
+	Hash          []byte
+	Owner         string
+	DataName      string
+	ReleaseTime   *big.Int
+	KeyReleased   bool
+	Phase         *big.Int
+}, error) {
+	return _TwoPhaseCommit.Contract.StoredData(&_TwoPhaseCommit.CallOpts, arg0)
+}
+
+// AddStoredData is a paid mutator transaction binding the contract method 0xa066b118.
+//
+// Solidity: function addStoredData(bytes _encryptedData, string _owner, string _dataName, uint256 _releaseTime, bytes _hash) returns()
+func (_TwoPhaseCommit *TwoPhaseCommitTransactor) AddStoredData(opts *bind.TransactOpts, _encryptedData []byte, _owner string, _dataName string, _releaseTime *big.Int, _hash []byte) (*types.Transaction, error) {
+	return _TwoPhaseCommit.contract.Transact(opts, "addStoredData", _encryptedData, _owner, _dataName, _releaseTime, _hash)
+}
+
+// AddStoredData is a paid mutator transaction binding the contract method 0xa066b118.
+//
+// Solidity: function addStoredData(bytes _encryptedData, string _owner, string _dataName, uint256 _releaseTime, bytes _hash) returns()
+func (_TwoPhaseCommit *TwoPhaseCommitSession) AddStoredData(_encryptedData []byte, _owner string, _dataName string, _releaseTime *big.Int, _hash []byte) (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.AddStoredData(&_TwoPhaseCommit.TransactOpts, _encryptedData, _owner, _dataName, _releaseTime, _hash)
+}
+
+// AddStoredData is a paid mutator transaction binding the contract method 0xa066b118.
+//
+// Solidity: function addStoredData(bytes _encryptedData, string _owner, string _dataName, uint256 _releaseTime, bytes _hash) returns()
+func (_TwoPhaseCommit *TwoPhaseCommitTransactorSession) AddStoredData(_encryptedData []byte, _owner string, _dataName string, _releaseTime *big.Int, _hash []byte) (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.AddStoredData(&_TwoPhaseCommit.TransactOpts, _encryptedData, _owner, _dataName, _releaseTime, _hash)
+}
+
+// ClearStoredData is a paid mutator transaction binding the contract method 0x4079e7cf.
+//
+// Solidity: function clearStoredData() returns()
+func (_TwoPhaseCommit *TwoPhaseCommitTransactor) ClearStoredData(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _TwoPhaseCommit.contract.Transact(opts, "clearStoredData")
+}
+
+// ClearStoredData is a paid mutator transaction binding the contract method 0x4079e7cf.
+//
+// Solidity: function clearStoredData() returns()
+func (_TwoPhaseCommit *TwoPhaseCommitSession) ClearStoredData() (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.ClearStoredData(&_TwoPhaseCommit.TransactOpts)
+}
+
+// ClearStoredData is a paid mutator transaction binding the contract method 0x4079e7cf.
+//
+// Solidity: function clearStoredData() returns()
+func (_TwoPhaseCommit *TwoPhaseCommitTransactorSession) ClearStoredData() (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.ClearStoredData(&_TwoPhaseCommit.TransactOpts)
+}
+
+// PerformUpkeep is a paid mutator transaction binding the contract method 0x4585e33b.
+//
+// Solidity: function performUpkeep(bytes ) returns()
+func (_TwoPhaseCommit *TwoPhaseCommitTransactor) PerformUpkeep(opts *bind.TransactOpts, arg0 []byte) (*types.Transaction, error) {
+	return _TwoPhaseCommit.contract.Transact(opts, "performUpkeep", arg0)
+}
+
+// PerformUpkeep is a paid mutator transaction binding the contract method 0x4585e33b.
+//
+// Solidity: function performUpkeep(bytes ) returns()
+func (_TwoPhaseCommit *TwoPhaseCommitSession) PerformUpkeep(arg0 []byte) (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.PerformUpkeep(&_TwoPhaseCommit.TransactOpts, arg0)
+}
+
+// PerformUpkeep is a paid mutator transaction binding the contract method 0x4585e33b.
+//
+// Solidity: function performUpkeep(bytes ) returns()
+func (_TwoPhaseCommit *TwoPhaseCommitTransactorSession) PerformUpkeep(arg0 []byte) (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.PerformUpkeep(&_TwoPhaseCommit.TransactOpts, arg0)
+}
+
+// ReleaseKey is a paid mutator transaction binding the contract method 0xcdc64266.
+//
+// Solidity: function releaseKey(string _dataName, string _owner, bytes _privateKey) returns()
+func (_TwoPhaseCommit *TwoPhaseCommitTransactor) ReleaseKey(opts *bind.TransactOpts, _dataName string, _owner string, _privateKey []byte) (*types.Transaction, error) {
+	return _TwoPhaseCommit.contract.Transact(opts, "releaseKey", _dataName, _owner, _privateKey)
+}
+
+// ReleaseKey is a paid mutator transaction binding the contract method 0xcdc64266.
+//
+// Solidity: function releaseKey(string _dataName, string _owner, bytes _privateKey) returns()
+func (_TwoPhaseCommit *TwoPhaseCommitSession) ReleaseKey(_dataName string, _owner string, _privateKey []byte) (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.ReleaseKey(&_TwoPhaseCommit.TransactOpts, _dataName, _owner, _privateKey)
+}
+
+// ReleaseKey is a paid mutator transaction binding the contract method 0xcdc64266.
+//
+// Solidity: function releaseKey(string _dataName, string _owner, bytes _privateKey) returns()
+func (_TwoPhaseCommit *TwoPhaseCommitTransactorSession) ReleaseKey(_dataName string, _owner string, _privateKey []byte) (*types.Transaction, error) {
+	return _TwoPhaseCommit.Contract.ReleaseKey(&_TwoPhaseCommit.TransactOpts, _dataName, _owner, _privateKey)
+}
+
+// TwoPhaseCommitKeyReleaseRequestedIterator is returned from FilterKeyReleaseRequested and is used to iterate over the raw logs and unpacked data for KeyReleaseRequested events raised by the TwoPhaseCommit contract.
+type TwoPhaseCommitKeyReleaseRequestedIterator struct {
+	Event *TwoPhaseCommitKeyReleaseRequested // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *TwoPhaseCommitKeyReleaseRequestedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(TwoPhaseCommitKeyReleaseRequested)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(TwoPhaseCommitKeyReleaseRequested)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *TwoPhaseCommitKeyReleaseRequestedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *TwoPhaseCommitKeyReleaseRequestedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// TwoPhaseCommitKeyReleaseRequested represents a KeyReleaseRequested event raised by the TwoPhaseCommit contract.
+type TwoPhaseCommitKeyReleaseRequested struct {
+	Index    *big.Int
+	Owner    string
+	DataName string
+	Raw      types.Log // Blockchain specific contextual infos
+}
+
+// FilterKeyReleaseRequested is a free log retrieval operation binding the contract event 0x4fad062119bf115b81cf5dec11e0c556c40723743a793ad1579939930e363857.
+//
+// Solidity: event KeyReleaseRequested(uint256 index, string owner, string dataName)
+func (_TwoPhaseCommit *TwoPhaseCommitFilterer) FilterKeyReleaseRequested(opts *bind.FilterOpts) (*TwoPhaseCommitKeyReleaseRequestedIterator, error) {
+
+	logs, sub, err := _TwoPhaseCommit.contract.FilterLogs(opts, "KeyReleaseRequested")
+	if err != nil {
+		return nil, err
+	}
+	return &TwoPhaseCommitKeyReleaseRequestedIterator{contract: _TwoPhaseCommit.contract, event: "KeyReleaseRequested", logs: logs, sub: sub}, nil
+}
+
+// WatchKeyReleaseRequested is a free log subscription operation binding the contract event 0x4fad062119bf115b81cf5dec11e0c556c40723743a793ad1579939930e363857.
+//
+// Solidity: event KeyReleaseRequested(uint256 index, string owner, string dataName)
+func (_TwoPhaseCommit *TwoPhaseCommitFilterer) WatchKeyReleaseRequested(opts *bind.WatchOpts, sink chan<- *TwoPhaseCommitKeyReleaseRequested) (event.Subscription, error) {
+
+	logs, sub, err := _TwoPhaseCommit.contract.WatchLogs(opts, "KeyReleaseRequested")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(TwoPhaseCommitKeyReleaseRequested)
+				if err := _TwoPhaseCommit.contract.UnpackLog(event, "KeyReleaseRequested", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseKeyReleaseRequested is a log parse operation binding the contract event 0x4fad062119bf115b81cf5dec11e0c556c40723743a793ad1579939930e363857.
+//
+// Solidity: event KeyReleaseRequested(uint256 index, string owner, string dataName)
+func (_TwoPhaseCommit *TwoPhaseCommitFilterer) ParseKeyReleaseRequested(log types.Log) (*TwoPhaseCommitKeyReleaseRequested, error) {
+	event := new(TwoPhaseCommitKeyReleaseRequested)
+	if err := _TwoPhaseCommit.contract.UnpackLog(event, "KeyReleaseRequested", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// TwoPhaseCommitKeyReleasedIterator is returned from FilterKeyReleased and is used to iterate over the raw logs and unpacked data for KeyReleased events raised by the TwoPhaseCommit contract.
+type TwoPhaseCommitKeyReleasedIterator struct {
+	Event *TwoPhaseCommitKeyReleased // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *TwoPhaseCommitKeyReleasedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(TwoPhaseCommitKeyReleased)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(TwoPhaseCommitKeyReleased)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *TwoPhaseCommitKeyReleasedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *TwoPhaseCommitKeyReleasedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// TwoPhaseCommitKeyReleased represents a KeyReleased event raised by the TwoPhaseCommit contract.
+type TwoPhaseCommitKeyReleased struct {
+	PrivateKey []byte
+	Owner      string
+	DataName   string
+	Raw        types.Log // Blockchain specific contextual infos
+}
+
+// FilterKeyReleased is a free log retrieval operation binding the contract event 0x65869521f90f9b11b2ee3589b995705d524c73a765fa1840c915f48c159a5ac8.
+//
+// Solidity: event KeyReleased(bytes privateKey, string owner, string dataName)
+func (_TwoPhaseCommit *TwoPhaseCommitFilterer) FilterKeyReleased(opts *bind.FilterOpts) (*TwoPhaseCommitKeyReleasedIterator, error) {
+
+	logs, sub, err := _TwoPhaseCommit.contract.FilterLogs(opts, "KeyReleased")
+	if err != nil {
+		return nil, err
+	}
+	return &TwoPhaseCommitKeyReleasedIterator{contract: _TwoPhaseCommit.contract, event: "KeyReleased", logs: logs, sub: sub}, nil
+}
+
+// WatchKeyReleased is a free log subscription operation binding the contract event 0x65869521f90f9b11b2ee3589b995705d524c73a765fa1840c915f48c159a5ac8.
+//
+// Solidity: event KeyReleased(bytes privateKey, string owner, string dataName)
+func (_TwoPhaseCommit *TwoPhaseCommitFilterer) WatchKeyReleased(opts *bind.WatchOpts, sink chan<- *TwoPhaseCommitKeyReleased) (event.Subscription, error) {
+
+	logs, sub, err := _TwoPhaseCommit.contract.WatchLogs(opts, "KeyReleased")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(TwoPhaseCommitKeyReleased)
+				if err := _TwoPhaseCommit.contract.UnpackLog(event, "KeyReleased", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseKeyReleased is a log parse operation binding the contract event 0x65869521f90f9b11b2ee3589b995705d524c73a765fa1840c915f48c159a5ac8.
+//
+// Solidity: event KeyReleased(bytes privateKey, string owner, string dataName)
+func (_TwoPhaseCommit *TwoPhaseCommitFilterer) ParseKeyReleased(log types.Log) (*TwoPhaseCommitKeyReleased, error) {
+	event := new(TwoPhaseCommitKeyReleased)
+	if err := _TwoPhaseCommit.contract.UnpackLog(event, "KeyReleased", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// TwoPhaseCommitReleaseEncryptedDataIterator is returned from FilterReleaseEncryptedData and is used to iterate over the raw logs and unpacked data for ReleaseEncryptedData events raised by the TwoPhaseCommit contract.
+type TwoPhaseCommitReleaseEncryptedDataIterator struct {
+	Event *TwoPhaseCommitReleaseEncryptedData // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *TwoPhaseCommitReleaseEncryptedDataIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(TwoPhaseCommitReleaseEncryptedData)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(TwoPhaseCommitReleaseEncryptedData)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *TwoPhaseCommitReleaseEncryptedDataIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *TwoPhaseCommitReleaseEncryptedDataIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// TwoPhaseCommitReleaseEncryptedData represents a ReleaseEncryptedData event raised by the TwoPhaseCommit contract.
+type TwoPhaseCommitReleaseEncryptedData struct {
+	EncryptedData []byte
+	Owner         string
+	DataName      string
+	ReleaseTime   *big.Int
+	Hash          []byte
+	Raw           types.Log // Blockchain specific contextual infos
+}
+
+// FilterReleaseEncryptedData is a free log retrieval operation binding the contract event 0x06e562cca2e90b8faf389cce052e937c1d69c2eff30ff8d64a8b337e078649f7.
+//
+// Solidity: event ReleaseEncryptedData(bytes encryptedData, string owner, string dataName, uint256 releaseTime, bytes hash)
+func (_TwoPhaseCommit *TwoPhaseCommitFilterer) FilterReleaseEncryptedData(opts *bind.FilterOpts) (*TwoPhaseCommitReleaseEncryptedDataIterator, error) {
+
+	logs, sub, err := _TwoPhaseCommit.contract.FilterLogs(opts, "ReleaseEncryptedData")
+	if err != nil {
+		return nil, err
+	}
+	return &TwoPhaseCommitReleaseEncryptedDataIterator{contract: _TwoPhaseCommit.contract, event: "ReleaseEncryptedData", logs: logs, sub: sub}, nil
+}
+
+// WatchReleaseEncryptedData is a free log subscription operation binding the contract event 0x06e562cca2e90b8faf389cce052e937c1d69c2eff30ff8d64a8b337e078649f7.
+//
+// Solidity: event ReleaseEncryptedData(bytes encryptedData, string owner, string dataName, uint256 releaseTime, bytes hash)
+func (_TwoPhaseCommit *TwoPhaseCommitFilterer) WatchReleaseEncryptedData(opts *bind.WatchOpts, sink chan<- *TwoPhaseCommitReleaseEncryptedData) (event.Subscription, error) {
+
+	logs, sub, err := _TwoPhaseCommit.contract.WatchLogs(opts, "ReleaseEncryptedData")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(TwoPhaseCommitReleaseEncryptedData)
+				if err := _TwoPhaseCommit.contract.UnpackLog(event, "ReleaseEncryptedData", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseReleaseEncryptedData is a log parse operation binding the contract event 0x06e562cca2e90b8faf389cce052e937c1d69c2eff30ff8d64a8b337e078649f7.
+//
+// Solidity: event ReleaseEncryptedData(bytes encryptedData, string owner, string dataName, uint256 releaseTime, bytes hash)
+func (_TwoPhaseCommit *TwoPhaseCommitFilterer) ParseReleaseEncryptedData(log types.Log) (*TwoPhaseCommitReleaseEncryptedData, error) {
+	event := new(TwoPhaseCommitReleaseEncryptedData)
+	if err := _TwoPhaseCommit.contract.UnpackLog(event, "ReleaseEncryptedData", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
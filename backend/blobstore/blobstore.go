@@ -0,0 +1,267 @@
+package blobstore
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+var (
+    ErrBlobNotFound   = errors.New("blob not found")
+    ErrDigestMismatch = errors.New("blob does not match its digest")
+    ErrInvalidLocator = errors.New("invalid blob locator")
+    ErrHostNotAllowed = errors.New("blob host is not allowed")
+    ErrBlobTooLarge   = errors.New("blob exceeds the size limit")
+)
+
+// DefaultMaxSize caps how much a Fetcher reads from a remote blob server
+const DefaultMaxSize = 64 << 20
+
+// Locators are stored on-chain in place of the ciphertext. They start with a
+// magic prefix and a version byte, followed by the sha256 digest of the blob
+// and the URL it can be read from.
+var locatorMagic = []byte("TPB")
+
+const locatorVersion = 1
+
+// Locator says where an off-chain blob lives and what it must hash to
+type Locator struct {
+    Digest [sha256.Size]byte
+    URL    string
+}
+
+// Hex returns the digest as hex, which is also the blob's key in a store
+func (l Locator) Hex() string {
+    return hex.EncodeToString(l.Digest[:])
+}
+
+// Encode serializes the locator for the contract's encryptedData field
+func (l Locator) Encode() []byte {
+    out := append([]byte{}, locatorMagic...)
+    out = append(out, locatorVersion)
+    out = append(out, l.Digest[:]...)
+    return append(out, l.URL...)
+}
+
+// IsLocator reports whether on-chain data is a locator rather than a ciphertext
+func IsLocator(data []byte) bool {
+    return len(data) > len(locatorMagic) && bytes.Equal(data[:len(locatorMagic)], locatorMagic)
+}
+
+// ParseLocator decodes a locator written by Encode
+func ParseLocator(data []byte) (Locator, error) {
+    header := len(locatorMagic) + 1
+    if !IsLocator(data) || len(data) < header+sha256.Size {
+        return Locator{}, ErrInvalidLocator
+    }
+    if data[len(locatorMagic)] != locatorVersion {
+        return Locator{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidLocator, data[len(locatorMagic)])
+    }
+
+    var l Locator
+    copy(l.Digest[:], data[header:header+sha256.Size])
+    l.URL = string(data[header+sha256.Size:])
+    return l, nil
+}
+
+// Store keeps blobs keyed by the sha256 digest of their content
+type Store interface {
+    Put(data []byte) ([sha256.Size]byte, error)
+    Get(digest [sha256.Size]byte) ([]byte, error)
+}
+
+// FileStore keeps each blob in its own file, sharded by the first digest byte
+type FileStore struct {
+    dir string
+}
+
+// NewFileStore opens (or creates) a blob store rooted at dir
+func NewFileStore(dir string) (*FileStore, error) {
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return nil, fmt.Errorf("failed to create blob store %s: %w", dir, err)
+    }
+    return &FileStore{dir: dir}, nil
+}
+
+func (fs *FileStore) path(digest [sha256.Size]byte) string {
+    name := hex.EncodeToString(digest[:])
+    return filepath.Join(fs.dir, name[:2], name)
+}
+
+// Put writes data under its digest. Writing the same content twice is a no-op.
+func (fs *FileStore) Put(data []byte) ([sha256.Size]byte, error) {
+    digest := sha256.Sum256(data)
+    path := fs.path(digest)
+    if _, err := os.Stat(path); err == nil {
+        return digest, nil
+    }
+
+    if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+        return digest, err
+    }
+    // Write to a temporary file first so readers never see a partial blob
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+    if err != nil {
+        return digest, err
+    }
+    defer os.Remove(tmp.Name())
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return digest, err
+    }
+    if err := tmp.Close(); err != nil {
+        return digest, err
+    }
+    return digest, os.Rename(tmp.Name(), path)
+}
+
+// Has reports whether a blob with digest is stored
+func (fs *FileStore) Has(digest [sha256.Size]byte) bool {
+    _, err := os.Stat(fs.path(digest))
+    return err == nil
+}
+
+// Delete removes a blob. Deleting a blob that is not stored is a no-op.
+func (fs *FileStore) Delete(digest [sha256.Size]byte) error {
+    if err := os.Remove(fs.path(digest)); err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    return nil
+}
+
+// Get reads a blob and checks it still matches its digest
+func (fs *FileStore) Get(digest [sha256.Size]byte) ([]byte, error) {
+    data, err := os.ReadFile(fs.path(digest))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, ErrBlobNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    if sha256.Sum256(data) != digest {
+        return nil, ErrDigestMismatch
+    }
+    return data, nil
+}
+
+// ParseDigest decodes a hex digest as used in blob URLs
+func ParseDigest(encoded string) ([sha256.Size]byte, error) {
+    var digest [sha256.Size]byte
+    raw, err := hex.DecodeString(encoded)
+    if err != nil || len(raw) != sha256.Size {
+        return digest, fmt.Errorf("digest must be %d bytes of hex", sha256.Size)
+    }
+    copy(digest[:], raw)
+    return digest, nil
+}
+
+// Fetcher resolves locators, preferring the local store and falling back to
+// the locator's URL for blobs uploaded through another server. Locators are
+// written by whoever uploads, so only URLs on AllowedHosts are followed and
+// at most MaxSize bytes are read.
+type Fetcher struct {
+    Local        Store
+    HTTPClient   *http.Client
+    AllowedHosts []string
+    MaxSize      int64
+}
+
+// NewFetcher creates a fetcher that reads from local first and otherwise
+// only from the given hosts (host or host:port)
+func NewFetcher(local Store, allowedHosts []string) *Fetcher {
+    f := &Fetcher{
+        Local:        local,
+        AllowedHosts: allowedHosts,
+        MaxSize:      DefaultMaxSize,
+    }
+    f.HTTPClient = &http.Client{
+        Timeout: 30 * time.Second,
+        CheckRedirect: func(req *http.Request, via []*http.Request) error {
+            if len(via) >= 5 {
+                return errors.New("too many redirects")
+            }
+            return f.checkURL(req.URL)
+        },
+    }
+    return f
+}
+
+// checkURL rejects URLs that are not plain http(s) on an allowed host
+func (f *Fetcher) checkURL(u *url.URL) error {
+    if u.Scheme != "http" && u.Scheme != "https" {
+        return fmt.Errorf("%w: unsupported scheme %q", ErrHostNotAllowed, u.Scheme)
+    }
+    for _, host := range f.AllowedHosts {
+        if strings.EqualFold(u.Host, host) || (u.Port() == "" && strings.EqualFold(u.Hostname(), host)) {
+            return nil
+        }
+    }
+    return fmt.Errorf("%w: %s", ErrHostNotAllowed, u.Host)
+}
+
+// Fetch returns the blob a locator points to, verified against its digest
+func (f *Fetcher) Fetch(ctx context.Context, l Locator) ([]byte, error) {
+    if f.Local != nil {
+        data, err := f.Local.Get(l.Digest)
+        if err == nil {
+            return data, nil
+        }
+        if !errors.Is(err, ErrBlobNotFound) {
+            return nil, err
+        }
+    }
+    if l.URL == "" {
+        return nil, ErrBlobNotFound
+    }
+
+    target, err := url.Parse(l.URL)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidLocator, err)
+    }
+    if err := f.checkURL(target); err != nil {
+        return nil, err
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.URL, nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := f.HTTPClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("failed to fetch blob from %s: %w", l.URL, err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode == http.StatusNotFound {
+        return nil, ErrBlobNotFound
+    }
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("blob server %s returned %d", l.URL, resp.StatusCode)
+    }
+
+    if resp.ContentLength > f.MaxSize {
+        return nil, ErrBlobTooLarge
+    }
+    data, err := io.ReadAll(io.LimitReader(resp.Body, f.MaxSize+1))
+    if err != nil {
+        return nil, err
+    }
+    if int64(len(data)) > f.MaxSize {
+        return nil, ErrBlobTooLarge
+    }
+    if sha256.Sum256(data) != l.Digest {
+        return nil, ErrDigestMismatch
+    }
+    return data, nil
+}
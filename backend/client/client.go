@@ -0,0 +1,160 @@
+package client
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+    h "web3server/helper"
+    "web3server/jobs"
+    "web3server/txmgr"
+
+    "github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Client talks to the backend in client-side encryption mode, so plaintext
+// never leaves the caller's machine.
+type Client struct {
+    BaseURL    string
+    HTTPClient *http.Client
+    // Storage selects where uploads keep their ciphertext: "chain" or "blob"
+    Storage string
+}
+
+// SealedData is a locally encrypted payload together with its release key
+type SealedData struct {
+    EncryptedData []byte
+    Hash          []byte
+    PrivateKey    []byte
+    Cipher        h.Cipher
+}
+
+// UploadResult mirrors the /upload response
+type UploadResult struct {
+    Message         string     `json:"message"`
+    JobID           string     `json:"jobId"`
+    TransactionHash string     `json:"transactionHash"`
+    Fees            txmgr.Fees `json:"fees"`
+    Cipher          string     `json:"cipher"`
+    Mode            string     `json:"mode"`
+    KeyEscrowed     bool       `json:"keyEscrowed"`
+    Storage         string     `json:"storage"`
+}
+
+// NewClient creates a client for the backend at baseURL, e.g. http://localhost:8080
+func NewClient(baseURL string) *Client {
+    return &Client{
+        BaseURL:    strings.TrimSuffix(baseURL, "/"),
+        HTTPClient: &http.Client{Timeout: 5 * time.Minute},
+    }
+}
+
+// Seal encrypts data locally with the given suite
+func Seal(suite h.Cipher, data string) (SealedData, error) {
+    encryptedData, privKey, err := h.EncryptDataWith(suite, data)
+    if err != nil {
+        return SealedData{}, fmt.Errorf("failed to encrypt data: %w", err)
+    }
+
+    return SealedData{
+        EncryptedData: encryptedData,
+        Hash:          h.RecordHash(encryptedData, h.KeyCommitment(privKey)),
+        PrivateKey:    privKey,
+        Cipher:        suite,
+    }, nil
+}
+
+// Upload relays sealed data to the contract through the backend. When
+// escrowKey is set the release key is handed to the backend so it can
+// publish it at releaseTime; otherwise the caller keeps it.
+func (c *Client) Upload(ctx context.Context, sealed SealedData, owner, dataName string, releaseTime uint64, escrowKey bool) (UploadResult, error) {
+    form := url.Values{}
+    form.Set("encryptedData", hexutil.Encode(sealed.EncryptedData))
+    form.Set("hash", hexutil.Encode(sealed.Hash))
+    form.Set("cipher", sealed.Cipher.Name())
+    form.Set("owner", owner)
+    form.Set("dataname", dataName)
+    form.Set("releaseTime", strconv.FormatUint(releaseTime, 10))
+    if c.Storage != "" {
+        form.Set("storage", c.Storage)
+    }
+    if escrowKey {
+        form.Set("releaseKey", hexutil.Encode(sealed.PrivateKey))
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/upload", strings.NewReader(form.Encode()))
+    if err != nil {
+        return UploadResult{}, err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+    var result UploadResult
+    if err := c.do(req, &result); err != nil {
+        return UploadResult{}, err
+    }
+    return result, nil
+}
+
+// Job fetches the current state of an upload job
+func (c *Client) Job(ctx context.Context, id string) (jobs.Job, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/jobs/"+url.PathEscape(id), nil)
+    if err != nil {
+        return jobs.Job{}, err
+    }
+
+    var job jobs.Job
+    if err := c.do(req, &job); err != nil {
+        return jobs.Job{}, err
+    }
+    return job, nil
+}
+
+// WaitJob polls an upload job until it reaches a final status or ctx is done
+func (c *Client) WaitJob(ctx context.Context, id string, interval time.Duration) (jobs.Job, error) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        job, err := c.Job(ctx, id)
+        if err != nil {
+            return jobs.Job{}, err
+        }
+        if job.Final() {
+            return job, nil
+        }
+
+        select {
+        case <-ticker.C:
+        case <-ctx.Done():
+            return job, ctx.Err()
+        }
+    }
+}
+
+// Decrypt opens a ciphertext fetched from the chain with a release key
+func Decrypt(encryptedData []byte, privateKey []byte) (string, error) {
+    return h.DecryptData(encryptedData, privateKey)
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+    resp, err := c.HTTPClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("request to %s failed: %w", req.URL, err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        var apiErr struct {
+            Error string `json:"error"`
+        }
+        json.NewDecoder(resp.Body).Decode(&apiErr)
+        return fmt.Errorf("backend returned %d: %s", resp.StatusCode, apiErr.Error)
+    }
+
+    return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+    "errors"
+    "fmt"
+    "math/big"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/ethereum/go-ethereum/common"
+    "gopkg.in/yaml.v3"
+)
+
+// Profile describes one network the backend can run against
+type Profile struct {
+    Name            string   `yaml:"-"`
+    Endpoints       []string `yaml:"endpoints"`
+    ChainID         uint64   `yaml:"chainId"`
+    ContractAddress string   `yaml:"contractAddress"`
+    Artifact        string   `yaml:"artifact"`
+    DeploymentBlock uint64   `yaml:"deploymentBlock"`
+    Upkeep          bool     `yaml:"upkeep"`
+}
+
+// Config is the set of named network profiles loaded from a YAML file
+type Config struct {
+    DefaultNetwork string              `yaml:"defaultNetwork"`
+    Networks       map[string]*Profile `yaml:"networks"`
+
+    path string
+}
+
+// Load reads the network profiles at path
+func Load(path string) (*Config, error) {
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read config file: %w", err)
+    }
+
+    var config Config
+    if err := yaml.Unmarshal(raw, &config); err != nil {
+        return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+    }
+    if len(config.Networks) == 0 {
+        return nil, fmt.Errorf("config file %s defines no networks", path)
+    }
+
+    config.path = path
+    for name, profile := range config.Networks {
+        if profile == nil {
+            return nil, fmt.Errorf("network %q is empty", name)
+        }
+        profile.Name = name
+    }
+    return &config, nil
+}
+
+// Profile returns the named profile, or the default one when name is empty
+func (c *Config) Profile(name string) (*Profile, error) {
+    if name == "" {
+        name = c.DefaultNetwork
+    }
+    profile, exists := c.Networks[name]
+    if !exists {
+        return nil, fmt.Errorf("unknown network %q, expected one of %v", name, c.Names())
+    }
+    if err := profile.validate(); err != nil {
+        return nil, fmt.Errorf("network %q: %w", name, err)
+    }
+    return profile, nil
+}
+
+// Names lists the configured profiles
+func (c *Config) Names() []string {
+    names := make([]string, 0, len(c.Networks))
+    for name := range c.Networks {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// ArtifactPath resolves the profile's artifact relative to the config file
+func (c *Config) ArtifactPath(profile *Profile) string {
+    if filepath.IsAbs(profile.Artifact) {
+        return profile.Artifact
+    }
+    return filepath.Join(filepath.Dir(c.path), profile.Artifact)
+}
+
+func (p *Profile) validate() error {
+    if len(p.Endpoints) == 0 {
+        return errors.New("no endpoints configured")
+    }
+    if p.ChainID == 0 {
+        return errors.New("chainId is required")
+    }
+    if p.Artifact == "" {
+        return errors.New("artifact is required")
+    }
+    if p.ContractAddress != "" && !common.IsHexAddress(p.ContractAddress) {
+        return fmt.Errorf("invalid contract address %q", p.ContractAddress)
+    }
+    return nil
+}
+
+// ChainIDBig returns the chain ID as used for signing
+func (p *Profile) ChainIDBig() *big.Int {
+    return new(big.Int).SetUint64(p.ChainID)
+}
+
+// Contract returns the deployed contract address
+func (p *Profile) Contract() common.Address {
+    return common.HexToAddress(p.ContractAddress)
+}
+
+// SetDeployment records a new contract address and deployment block for the
+// named profile and writes it back to the config file. Only the affected
+// lines are rewritten, so comments and layout are left as they were.
+func (c *Config) SetDeployment(name string, address common.Address, block uint64) error {
+    profile, exists := c.Networks[name]
+    if !exists {
+        return fmt.Errorf("unknown network %q", name)
+    }
+
+    raw, err := os.ReadFile(c.path)
+    if err != nil {
+        return fmt.Errorf("failed to read config file: %w", err)
+    }
+    var doc yaml.Node
+    if err := yaml.Unmarshal(raw, &doc); err != nil {
+        return fmt.Errorf("failed to parse config file %s: %w", c.path, err)
+    }
+    if len(doc.Content) == 0 {
+        return fmt.Errorf("config file %s is empty", c.path)
+    }
+    node := mappingValue(mappingValue(doc.Content[0], "networks"), name)
+    if node == nil || node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+        return fmt.Errorf("network %q not found in %s", name, c.path)
+    }
+
+    lines := strings.Split(string(raw), "\n")
+    lines = setLine(lines, node, "deploymentBlock", strconv.FormatUint(block, 10))
+    lines = setLine(lines, node, "contractAddress", strconv.Quote(address.Hex()))
+
+    file, err := os.CreateTemp(filepath.Dir(c.path), ".networks-*.yaml")
+    if err != nil {
+        return fmt.Errorf("failed to write config file: %w", err)
+    }
+    defer os.Remove(file.Name())
+
+    if _, err := file.WriteString(strings.Join(lines, "\n")); err != nil {
+        file.Close()
+        return fmt.Errorf("failed to write config file: %w", err)
+    }
+    if err := file.Close(); err != nil {
+        return fmt.Errorf("failed to write config file: %w", err)
+    }
+    if err := os.Rename(file.Name(), c.path); err != nil {
+        return fmt.Errorf("failed to replace config file: %w", err)
+    }
+
+    profile.ContractAddress = address.Hex()
+    profile.DeploymentBlock = block
+    return nil
+}
+
+// mappingValue returns the value stored under key in a YAML mapping
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+    if node == nil || node.Kind != yaml.MappingNode {
+        return nil
+    }
+    for i := 0; i+1 < len(node.Content); i += 2 {
+        if node.Content[i].Value == key {
+            return node.Content[i+1]
+        }
+    }
+    return nil
+}
+
+// setLine rewrites the line holding key in mapping, or adds one after the
+// mapping's last line. Lines are only ever added after those of the node,
+// so positions of earlier keys stay valid.
+func setLine(lines []string, mapping *yaml.Node, key, value string) []string {
+    indent := strings.Repeat(" ", mapping.Content[0].Column-1)
+    for i := 0; i+1 < len(mapping.Content); i += 2 {
+        if mapping.Content[i].Value == key {
+            line := mapping.Content[i].Line - 1
+            lines[line] = indent + key + ": " + value
+            return lines
+        }
+    }
+
+    last := lastLine(mapping)
+    lines = append(lines[:last], append([]string{indent + key + ": " + value}, lines[last:]...)...)
+    return lines
+}
+
+// lastLine is the highest line number used by node or its children
+func lastLine(node *yaml.Node) int {
+    last := node.Line
+    for _, child := range node.Content {
+        if line := lastLine(child); line > last {
+            last = line
+        }
+    }
+    return last
+}
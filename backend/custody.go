@@ -0,0 +1,184 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+
+    "web3server/custody"
+    h "web3server/helper"
+    ks "web3server/keystore"
+
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/common/hexutil"
+    "github.com/gin-gonic/gin"
+)
+
+// setupCustody splits release keys across CUSTODY_CUSTODIANS in-process
+// custodians, CUSTODY_THRESHOLD of which are needed to rebuild a key.
+// CUSTODY_KEYS registers the addresses custodians sign submitted shares
+// with, as "custodian-1=0x...,custodian-2=0x...". Custody is disabled when
+// no custodians are configured.
+func setupCustody(masterKey []byte) (*custody.Coordinator, func(), error) {
+    total, err := strconv.Atoi(GetEnvDefault("CUSTODY_CUSTODIANS", "0"))
+    if err != nil || total == 0 {
+        return nil, func() {}, err
+    }
+    threshold, err := strconv.Atoi(GetEnvDefault("CUSTODY_THRESHOLD", strconv.Itoa(total/2+1)))
+    if err != nil {
+        return nil, func() {}, err
+    }
+
+    signers, err := parseCustodianKeys(os.Getenv("CUSTODY_KEYS"))
+    if err != nil {
+        return nil, func() {}, err
+    }
+
+    dir := GetEnvDefault("CUSTODY_DIR", "custody")
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return nil, func() {}, err
+    }
+
+    var stores []ks.KeyStore
+    closeAll := func() {
+        for _, store := range stores {
+            store.Close()
+        }
+    }
+
+    var custodians []custody.Custodian
+    for i := 1; i <= total; i++ {
+        id := fmt.Sprintf("custodian-%d", i)
+        store, err := ks.NewBoltKeyStore(filepath.Join(dir, id+".db"), masterKey)
+        if err != nil {
+            closeAll()
+            return nil, func() {}, err
+        }
+        stores = append(stores, store)
+        custodians = append(custodians, custody.NewLocalCustodian(id, store))
+    }
+
+    assignments, err := ks.NewBoltKeyStore(filepath.Join(dir, "assignments.db"), masterKey)
+    if err != nil {
+        closeAll()
+        return nil, func() {}, err
+    }
+    stores = append(stores, assignments)
+
+    coordinator, err := custody.NewCoordinator(custody.CoordinatorConfig{
+        Custodians: custodians,
+        Threshold:  threshold,
+        Store:      assignments,
+        Keys:       signers,
+    })
+    if err != nil {
+        closeAll()
+        return nil, func() {}, err
+    }
+    return coordinator, closeAll, nil
+}
+
+func parseCustodianKeys(value string) (map[string]common.Address, error) {
+    signers := make(map[string]common.Address)
+    for _, entry := range strings.Split(value, ",") {
+        if strings.TrimSpace(entry) == "" {
+            continue
+        }
+        id, address, found := strings.Cut(strings.TrimSpace(entry), "=")
+        if !found || !common.IsHexAddress(address) {
+            return nil, fmt.Errorf("invalid CUSTODY_KEYS entry %q", entry)
+        }
+        signers[id] = common.HexToAddress(address)
+    }
+    return signers, nil
+}
+
+// storeReleaseKey hands a key to the custodians, or to the keystore when
+// custody is disabled
+func storeReleaseKey(owner, dataName string, key []byte, releaseTime uint64) error {
+    if coordinator != nil {
+        return coordinator.Distribute(owner, dataName, key, releaseTime)
+    }
+    return keys.Put(owner, dataName, key)
+}
+
+// lookupReleaseKey rebuilds a key from custodian shares, falling back to the
+// keystore for records uploaded before custody was enabled
+func lookupReleaseKey(owner, dataName string) ([]byte, error) {
+    if coordinator != nil {
+        key, err := coordinator.Combine(owner, dataName)
+        if !errors.Is(err, custody.ErrUnknownRecord) {
+            return key, err
+        }
+    }
+    return keys.Get(owner, dataName)
+}
+
+func postShare(c *gin.Context) {
+    if coordinator == nil {
+        c.JSON(404, gin.H{"error": "Key custody is not enabled"})
+        return
+    }
+
+    custodianID := c.PostForm("custodian")
+    owner := c.PostForm("owner")
+    dataName := c.PostForm("dataname")
+
+    encoded, err := hexutil.Decode(c.PostForm("share"))
+    if err != nil {
+        c.JSON(400, gin.H{"error": fmt.Sprintf("Share must be 0x-prefixed hex: %v", err)})
+        return
+    }
+    share, err := h.ParseShare(encoded)
+    if err != nil {
+        c.JSON(400, gin.H{"error": err.Error()})
+        return
+    }
+
+    signature, err := hexutil.Decode(c.PostForm("signature"))
+    if err != nil {
+        c.JSON(401, gin.H{"error": fmt.Sprintf("Signature must be 0x-prefixed hex: %v", err)})
+        return
+    }
+
+    if err := coordinator.Submit(custodianID, owner, dataName, share, signature); err != nil {
+        if errors.Is(err, custody.ErrUnauthorized) {
+            c.JSON(401, gin.H{"error": err.Error()})
+            return
+        }
+        if errors.Is(err, custody.ErrUnknownRecord) {
+            c.JSON(404, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(400, gin.H{"error": err.Error()})
+        return
+    }
+
+    status, err := coordinator.Status(owner, dataName)
+    if err != nil {
+        c.JSON(500, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(200, status)
+}
+
+func getCustodyStatus(c *gin.Context) {
+    if coordinator == nil {
+        c.JSON(404, gin.H{"error": "Key custody is not enabled"})
+        return
+    }
+
+    status, err := coordinator.Status(c.Param("owner"), c.Param("dataname"))
+    if errors.Is(err, custody.ErrUnknownRecord) {
+        c.JSON(404, gin.H{"error": "Record has no key shares"})
+        return
+    }
+    if err != nil {
+        c.JSON(500, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(200, status)
+}
@@ -0,0 +1,401 @@
+package custody
+
+import (
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+
+    h "web3server/helper"
+    ks "web3server/keystore"
+
+    "github.com/ethereum/go-ethereum/accounts"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/common/hexutil"
+    "github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+    ErrNotReleased     = errors.New("share is still locked")
+    ErrUnknownRecord   = errors.New("record has no key shares")
+    ErrThresholdNotMet = errors.New("not enough shares to rebuild the key")
+    ErrUnauthorized    = errors.New("share is not signed by the custodian's registered key")
+)
+
+// Custodian holds one share of each release key and hands it back once the
+// record's release time has passed. Discard drops a share whose
+// distribution did not complete.
+type Custodian interface {
+    ID() string
+    Hold(owner, dataName string, share h.Share, releaseTime uint64) error
+    Release(owner, dataName string) (h.Share, error)
+    Discard(owner, dataName string) error
+}
+
+// LocalCustodian is an in-process custodian with its own sealed store
+type LocalCustodian struct {
+    id    string
+    store ks.KeyStore
+}
+
+// NewLocalCustodian creates a custodian backed by store
+func NewLocalCustodian(id string, store ks.KeyStore) *LocalCustodian {
+    return &LocalCustodian{id: id, store: store}
+}
+
+func (lc *LocalCustodian) ID() string {
+    return lc.id
+}
+
+func (lc *LocalCustodian) Hold(owner, dataName string, share h.Share, releaseTime uint64) error {
+    value := binary.BigEndian.AppendUint64(nil, releaseTime)
+    return lc.store.Put(owner, dataName, append(value, share.Bytes()...))
+}
+
+func (lc *LocalCustodian) Release(owner, dataName string) (h.Share, error) {
+    value, err := lc.store.Get(owner, dataName)
+    if err != nil {
+        return h.Share{}, err
+    }
+    if len(value) < 8 {
+        return h.Share{}, fmt.Errorf("corrupt share held by %s", lc.id)
+    }
+    if releaseTime := binary.BigEndian.Uint64(value); uint64(time.Now().Unix()) < releaseTime {
+        return h.Share{}, ErrNotReleased
+    }
+    return h.ParseShare(value[8:])
+}
+
+func (lc *LocalCustodian) Discard(owner, dataName string) error {
+    return lc.store.Delete(owner, dataName)
+}
+
+// CoordinatorConfig describes the M-of-N sharing across custodians. Keys
+// registers the address each custodian signs submitted shares with; a
+// custodian without one can only hand its share over in-process.
+type CoordinatorConfig struct {
+    Custodians []Custodian
+    Threshold  int
+    Store      ks.KeyStore
+    Keys       map[string]common.Address
+}
+
+// Assignment records which share went to which custodian for a record
+type Assignment struct {
+    Owner       string          `json:"owner"`
+    DataName    string          `json:"dataName"`
+    ReleaseTime uint64          `json:"releaseTime"`
+    Threshold   int             `json:"threshold"`
+    Custodians  map[string]byte `json:"custodians"`
+    Commitment  []byte          `json:"commitment"`
+}
+
+// Status reports which custodians have contributed their share
+type Status struct {
+    Owner       string   `json:"owner"`
+    DataName    string   `json:"dataName"`
+    Threshold   int      `json:"threshold"`
+    Total       int      `json:"total"`
+    Contributed []string `json:"contributed"`
+    Pending     []string `json:"pending"`
+    Ready       bool     `json:"ready"`
+}
+
+// Coordinator splits release keys across custodians and rebuilds them from
+// the shares collected at release time. It never stores a whole key.
+// Shares submitted by remote custodians are kept in memory only, so after a
+// restart they show as pending again and their custodians must resubmit.
+type Coordinator struct {
+    Config    CoordinatorConfig
+    submitted map[string]map[string]h.Share
+    mu        sync.Mutex
+}
+
+// NewCoordinator validates the sharing parameters
+func NewCoordinator(config CoordinatorConfig) (*Coordinator, error) {
+    if config.Threshold < 1 || config.Threshold > len(config.Custodians) {
+        return nil, fmt.Errorf("threshold %d is invalid for %d custodians", config.Threshold, len(config.Custodians))
+    }
+    if config.Store == nil {
+        return nil, errors.New("coordinator requires a store for assignments")
+    }
+
+    return &Coordinator{
+        Config:    config,
+        submitted: make(map[string]map[string]h.Share),
+    }, nil
+}
+
+func recordKey(owner, dataName string) string {
+    return string(ks.RecordKey(owner, dataName))
+}
+
+// Distribute splits key and hands one share to every custodian. A record
+// that already has shares is left alone. If a custodian fails, the shares
+// already handed out are discarded so the distribution can be retried.
+func (c *Coordinator) Distribute(owner, dataName string, key []byte, releaseTime uint64) error {
+    if _, err := c.assignment(owner, dataName); !errors.Is(err, ErrUnknownRecord) {
+        if err == nil {
+            err = fmt.Errorf("%w for %s/%s", ks.ErrKeyExists, owner, dataName)
+        }
+        return err
+    }
+
+    shares, err := h.SplitSecret(key, len(c.Config.Custodians), c.Config.Threshold)
+    if err != nil {
+        return err
+    }
+
+    assignment := Assignment{
+        Owner:       owner,
+        DataName:    dataName,
+        ReleaseTime: releaseTime,
+        Threshold:   c.Config.Threshold,
+        Custodians:  make(map[string]byte),
+        Commitment:  h.KeyCommitment(key),
+    }
+    for i, custodian := range c.Config.Custodians {
+        err := custodian.Hold(owner, dataName, shares[i], releaseTime)
+        if errors.Is(err, ks.ErrKeyExists) {
+            // Without an assignment, a held share is left over from a
+            // distribution that was interrupted before it was recorded
+            if err = custodian.Discard(owner, dataName); err == nil {
+                err = custodian.Hold(owner, dataName, shares[i], releaseTime)
+            }
+        }
+        if err != nil {
+            err = fmt.Errorf("custodian %s failed to hold share: %w", custodian.ID(), err)
+            return c.discard(owner, dataName, c.Config.Custodians[:i], err)
+        }
+        assignment.Custodians[custodian.ID()] = shares[i].X
+    }
+
+    encoded, err := json.Marshal(assignment)
+    if err == nil {
+        err = c.Config.Store.Put(owner, dataName, encoded)
+    }
+    if err != nil {
+        err = fmt.Errorf("failed to store share assignment: %w", err)
+        return c.discard(owner, dataName, c.Config.Custodians, err)
+    }
+
+    c.mu.Lock()
+    delete(c.submitted, recordKey(owner, dataName))
+    c.mu.Unlock()
+    return nil
+}
+
+// discard rolls back a failed distribution and returns its cause, noting
+// any custodian that could not drop its share
+func (c *Coordinator) discard(owner, dataName string, custodians []Custodian, cause error) error {
+    for _, custodian := range custodians {
+        if err := custodian.Discard(owner, dataName); err != nil {
+            cause = fmt.Errorf("%w (custodian %s kept its share: %v)", cause, custodian.ID(), err)
+        }
+    }
+    return cause
+}
+
+func (c *Coordinator) assignment(owner, dataName string) (Assignment, error) {
+    encoded, err := c.Config.Store.Get(owner, dataName)
+    if errors.Is(err, ks.ErrKeyNotFound) {
+        return Assignment{}, ErrUnknownRecord
+    }
+    if err != nil {
+        return Assignment{}, err
+    }
+
+    var assignment Assignment
+    if err := json.Unmarshal(encoded, &assignment); err != nil {
+        return Assignment{}, fmt.Errorf("corrupt share assignment: %w", err)
+    }
+    return assignment, nil
+}
+
+// ShareMessage is the text a custodian signs, EIP-191 style, to submit a share
+func ShareMessage(custodianID, owner, dataName string, share h.Share) []byte {
+    return []byte(fmt.Sprintf("TwoPhaseCommit key share\ncustodian: %s\nowner: %s\ndataName: %s\nshare: %s",
+        custodianID, owner, dataName, hexutil.Encode(share.Bytes())))
+}
+
+// Submit records a share contributed by a remote custodian. The signature
+// must be a 65-byte personal_sign signature of ShareMessage by the key
+// registered for the custodian. The share is not persisted; a restart
+// before the key is combined means submitting it again.
+func (c *Coordinator) Submit(custodianID, owner, dataName string, share h.Share, signature []byte) error {
+    registered, ok := c.Config.Keys[custodianID]
+    if !ok {
+        return fmt.Errorf("%w: custodian %s has no registered key", ErrUnauthorized, custodianID)
+    }
+    if len(signature) != crypto.SignatureLength {
+        return fmt.Errorf("%w: signature must be %d bytes", ErrUnauthorized, crypto.SignatureLength)
+    }
+    sig := append([]byte{}, signature...)
+    if sig[crypto.RecoveryIDOffset] >= 27 {
+        sig[crypto.RecoveryIDOffset] -= 27
+    }
+    pub, err := crypto.SigToPub(accounts.TextHash(ShareMessage(custodianID, owner, dataName, share)), sig)
+    if err != nil || crypto.PubkeyToAddress(*pub) != registered {
+        return ErrUnauthorized
+    }
+    return c.accept(custodianID, owner, dataName, share)
+}
+
+// accept records a share after checking it is the one assigned to the custodian
+func (c *Coordinator) accept(custodianID, owner, dataName string, share h.Share) error {
+    assignment, err := c.assignment(owner, dataName)
+    if err != nil {
+        return err
+    }
+
+    x, assigned := assignment.Custodians[custodianID]
+    if !assigned {
+        return fmt.Errorf("custodian %s holds no share for %s/%s", custodianID, owner, dataName)
+    }
+    if share.X != x {
+        return fmt.Errorf("custodian %s submitted share %d, expected %d", custodianID, share.X, x)
+    }
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    key := recordKey(owner, dataName)
+    if c.submitted[key] == nil {
+        c.submitted[key] = make(map[string]h.Share)
+    }
+    c.submitted[key][custodianID] = share
+    return nil
+}
+
+// collect asks in-process custodians for any share they are ready to
+// release. Their own share replaces whatever was submitted in their name.
+func (c *Coordinator) collect(assignment Assignment) {
+    for _, custodian := range c.Config.Custodians {
+        share, err := custodian.Release(assignment.Owner, assignment.DataName)
+        if err != nil {
+            continue
+        }
+        c.accept(custodian.ID(), assignment.Owner, assignment.DataName, share)
+    }
+}
+
+// Combine collects shares and rebuilds the release key once the threshold
+// is met. The result is checked against the commitment taken at upload; if
+// a bad share spoils it, other subsets are tried and the shares that do not
+// fit the recovered key are dropped so their custodians can resubmit.
+func (c *Coordinator) Combine(owner, dataName string) ([]byte, error) {
+    assignment, err := c.assignment(owner, dataName)
+    if err != nil {
+        return nil, err
+    }
+    c.collect(assignment)
+
+    c.mu.Lock()
+    var ids []string
+    var shares []h.Share
+    for id, share := range c.submitted[recordKey(owner, dataName)] {
+        ids = append(ids, id)
+        shares = append(shares, share)
+    }
+    c.mu.Unlock()
+
+    if len(shares) < assignment.Threshold {
+        return nil, fmt.Errorf("%w: have %d of %d", ErrThresholdNotMet, len(shares), assignment.Threshold)
+    }
+
+    key, err := h.CombineShares(shares)
+    if err == nil && h.VerifyKeyCommitment(assignment.Commitment, key) {
+        return key, nil
+    }
+
+    subset, key := c.search(assignment, shares)
+    if key == nil {
+        return nil, fmt.Errorf("no %d of the %d submitted shares match the key commitment", assignment.Threshold, len(shares))
+    }
+
+    // Any share that rebuilds the key alongside threshold-1 good ones is good
+    good := make(map[int]bool)
+    base := make([]h.Share, 0, assignment.Threshold)
+    for _, i := range subset {
+        good[i] = true
+    }
+    for _, i := range subset[:assignment.Threshold-1] {
+        base = append(base, shares[i])
+    }
+    c.mu.Lock()
+    for i, share := range shares {
+        if good[i] {
+            continue
+        }
+        combined, err := h.CombineShares(append(base[:len(base):len(base)], share))
+        if err == nil && h.VerifyKeyCommitment(assignment.Commitment, combined) {
+            continue
+        }
+        delete(c.submitted[recordKey(owner, dataName)], ids[i])
+    }
+    c.mu.Unlock()
+    return key, nil
+}
+
+// search tries every threshold-sized subset of shares and returns the first
+// whose key matches the commitment, or a nil key
+func (c *Coordinator) search(assignment Assignment, shares []h.Share) ([]int, []byte) {
+    subset := make([]int, assignment.Threshold)
+    var try func(start, depth int) []byte
+    try = func(start, depth int) []byte {
+        if depth == len(subset) {
+            picked := make([]h.Share, len(subset))
+            for i, index := range subset {
+                picked[i] = shares[index]
+            }
+            key, err := h.CombineShares(picked)
+            if err != nil || !h.VerifyKeyCommitment(assignment.Commitment, key) {
+                return nil
+            }
+            return key
+        }
+        for i := start; i <= len(shares)-(len(subset)-depth); i++ {
+            subset[depth] = i
+            if key := try(i+1, depth+1); key != nil {
+                return key
+            }
+        }
+        return nil
+    }
+    return subset, try(0, 0)
+}
+
+// Status reports the contributions for a record
+func (c *Coordinator) Status(owner, dataName string) (Status, error) {
+    assignment, err := c.assignment(owner, dataName)
+    if err != nil {
+        return Status{}, err
+    }
+
+    c.mu.Lock()
+    submitted := c.submitted[recordKey(owner, dataName)]
+    status := Status{
+        Owner:       owner,
+        DataName:    dataName,
+        Threshold:   assignment.Threshold,
+        Total:       len(assignment.Custodians),
+        Contributed: []string{},
+        Pending:     []string{},
+    }
+    for id := range assignment.Custodians {
+        if _, contributed := submitted[id]; contributed {
+            status.Contributed = append(status.Contributed, id)
+        } else {
+            status.Pending = append(status.Pending, id)
+        }
+    }
+    c.mu.Unlock()
+
+    sort.Strings(status.Contributed)
+    sort.Strings(status.Pending)
+    status.Ready = len(status.Contributed) >= status.Threshold
+    return status, nil
+}
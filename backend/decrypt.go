@@ -0,0 +1,164 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "web3server/blobstore"
+    "web3server/custody"
+    h "web3server/helper"
+    "web3server/indexer"
+    ks "web3server/keystore"
+
+    "github.com/gin-gonic/gin"
+)
+
+// decryptData returns the plaintext of a record once its release key is available
+func decryptData(c *gin.Context) {
+    dataName := c.Param("dataname")
+    owner := c.Param("owner")
+    ctx := context.Background()
+
+    record, err := fetchPublicData(ctx, dataName, owner)
+    if errors.Is(err, blobstore.ErrDigestMismatch) {
+        c.JSON(409, gin.H{"error": "Off-chain blob does not match the stored hash"})
+        return
+    }
+    if errors.Is(err, blobstore.ErrBlobNotFound) {
+        c.JSON(404, gin.H{"error": "Off-chain blob not found"})
+        return
+    }
+    if err != nil {
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to fetch data: %v", err)})
+        return
+    }
+    if record.ReleaseTime.Sign() == 0 {
+        c.JSON(404, gin.H{"error": "Record not found"})
+        return
+    }
+
+    if !h.VerifyCiphertextHash(record.EncryptedData, record.Hash) {
+        c.JSON(409, gin.H{"error": "Encrypted data does not match the stored hash"})
+        return
+    }
+
+    header, err := client.HeaderByNumber(ctx, nil)
+    if err != nil {
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to fetch latest block: %v", err)})
+        return
+    }
+
+    releaseTime := record.ReleaseTime.Uint64()
+    if header.Time < releaseTime {
+        c.JSON(423, gin.H{
+            "error":       fmt.Sprintf("Data is locked until %s", time.Unix(int64(releaseTime), 0).UTC().Format(time.RFC3339)),
+            "releaseTime": releaseTime,
+        })
+        return
+    }
+
+    _, commitment := h.SplitRecordHash(record.Hash)
+    key, source, err := findReleasedKey(dataName, owner, commitment)
+    if errors.Is(err, ks.ErrKeyNotFound) || errors.Is(err, custody.ErrThresholdNotMet) || errors.Is(err, errKeyNotPublished) {
+        c.JSON(404, gin.H{"error": "Release key has not been published"})
+        return
+    }
+    if errors.Is(err, errIndexNotLive) {
+        c.JSON(503, gin.H{"error": "Event index is still catching up with the chain, try again shortly"})
+        return
+    }
+    if err != nil {
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to find release key: %v", err)})
+        return
+    }
+
+    // Refuse keys that do not match the commitment published at upload
+    if commitment != nil && !h.VerifyKeyCommitment(commitment, key) {
+        c.JSON(409, gin.H{"error": fmt.Sprintf("Release key from %s does not match the published commitment", source)})
+        return
+    }
+
+    plaintext, err := h.DecryptData(record.EncryptedData, key)
+    if err != nil {
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to decrypt data: %v", err)})
+        return
+    }
+
+    c.JSON(200, gin.H{
+        "data":        plaintext,
+        "owner":       owner,
+        "dataName":    dataName,
+        "releaseTime": releaseTime,
+        "keySource":   source,
+    })
+}
+
+// errKeyNotPublished means upkeep has not yet moved the record to phase 2,
+// so the escrowed key may not be handed out
+var errKeyNotPublished = errors.New("release key has not been published")
+
+// errIndexNotLive means the event index is still catching up with the chain
+// and cannot yet tell whether a key was published
+var errIndexNotLive = errors.New("event index is still catching up")
+
+// findReleasedKey looks for a published key in KeyReleased events and falls
+// back to the escrowed key once the record reaches phase 2. releaseKey has
+// no access control, so only event keys that open the record's commitment
+// count; records uploaded without a commitment take the first key. Only the
+// local index is consulted, so a request never scans the chain.
+func findReleasedKey(dataName, owner string, commitment []byte) ([]byte, string, error) {
+    released, err := events.Events(indexer.Filter{
+        Name:          indexer.EventKeyReleased,
+        Owner:         owner,
+        DataName:      dataName,
+        ConfirmedOnly: true,
+    })
+    if err != nil {
+        return nil, "", fmt.Errorf("failed to read KeyReleased events: %v", err)
+    }
+    for _, event := range released {
+        if opensCommitment(commitment, event.PrivateKey) {
+            return event.PrivateKey, "event", nil
+        }
+    }
+
+    if !events.Status().Live {
+        return nil, "", errIndexNotLive
+    }
+    if !reachedPhaseTwo(dataName, owner, released) {
+        return nil, "", errKeyNotPublished
+    }
+
+    key, err := lookupReleaseKey(owner, dataName)
+    if err != nil {
+        return nil, "", err
+    }
+    return key, "keystore", nil
+}
+
+// opensCommitment reports whether a published key is usable for a record.
+// performUpkeep emits KeyReleased with an empty key, which never is.
+func opensCommitment(commitment, key []byte) bool {
+    if len(key) == 0 {
+        return false
+    }
+    return commitment == nil || h.VerifyKeyCommitment(commitment, key)
+}
+
+// reachedPhaseTwo reports whether upkeep has moved the record to phase 2,
+// from the catalog or from the empty-key KeyReleased event upkeep emits
+func reachedPhaseTwo(dataName, owner string, released []indexer.Event) bool {
+    if catalog != nil {
+        if record, exists, err := catalog.Get(owner, dataName); err == nil && exists && record.Phase >= 2 {
+            return true
+        }
+    }
+    for _, event := range released {
+        if len(event.PrivateKey) == 0 {
+            return true
+        }
+    }
+    return false
+}
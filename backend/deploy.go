@@ -0,0 +1,288 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "log"
+    "math/big"
+    "os"
+    "time"
+
+    "web3server/bindings"
+    "web3server/config"
+    "web3server/rpcpool"
+    "web3server/signer"
+    "web3server/txmgr"
+
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/core/types"
+    "github.com/joho/godotenv"
+)
+
+// migrationMargin keeps records whose release time would pass before their
+// addStoredData is mined from being sent, since the contract rejects them
+const migrationMargin = 5 * 60
+
+// runDeploy is the "deploy" command: it deploys TwoPhaseCommit from the
+// profile's artifact, waits for it to confirm and records the address and
+// deployment block in the network profile. With -migrate the records of the
+// contract being replaced are added to the new one.
+func runDeploy(args []string) {
+    godotenv.Load()
+
+    fs := flag.NewFlagSet("deploy", flag.ExitOnError)
+    configPath := fs.String("config", GetEnvDefault("NETWORK_CONFIG", "networks.yaml"), "network profiles file")
+    networkName := fs.String("network", os.Getenv("NETWORK"), "network profile to deploy to")
+    artifactPath := fs.String("artifact", "", "artifact to deploy instead of the profile's")
+    redeploy := fs.Bool("redeploy", false, "replace the contract the profile already points at")
+    migrate := fs.Bool("migrate", false, "copy records from the replaced contract into the new one")
+    migrateFrom := fs.String("migrate-from", "", "copy records from this contract instead of the replaced one")
+    noDeploy := fs.Bool("no-deploy", false, "skip the deployment and migrate -migrate-from into the profile's contract")
+    confirmations := fs.Uint64("confirmations", 1, "blocks to wait for before recording the deployment")
+    timeout := fs.Duration("timeout", 10*time.Minute, "give up after this long")
+    fs.Parse(args)
+
+    networks, err := config.Load(*configPath)
+    if err != nil {
+        log.Fatalf("Failed to load network config: %v", err)
+    }
+    profile, err := networks.Profile(*networkName)
+    if err != nil {
+        log.Fatalf("Failed to select network: %v", err)
+    }
+    if *noDeploy && (*migrateFrom == "" || profile.ContractAddress == "") {
+        log.Fatalf("-no-deploy needs -migrate-from and a contract address in network %s", profile.Name)
+    }
+    if profile.ContractAddress != "" && !*redeploy && !*noDeploy {
+        log.Fatalf("Network %s already uses contract %s; pass -redeploy to replace it", profile.Name, profile.ContractAddress)
+    }
+
+    var source *common.Address
+    switch {
+    case *migrateFrom != "":
+        if !common.IsHexAddress(*migrateFrom) {
+            log.Fatalf("Invalid -migrate-from address %q", *migrateFrom)
+        }
+        address := common.HexToAddress(*migrateFrom)
+        source = &address
+    case *migrate:
+        if profile.ContractAddress == "" {
+            log.Fatalf("Network %s has no contract to migrate records from", profile.Name)
+        }
+        address := profile.Contract()
+        source = &address
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+    defer cancel()
+
+    deployer, err := newSignerFromEnv(ctx)
+    if err != nil {
+        log.Fatalf("Failed to set up transaction signer: %v", err)
+    }
+    strategy, err := txmgr.StrategyByName(GetEnvDefault("GAS_STRATEGY", "normal"), nil)
+    if err != nil {
+        log.Fatalf("Failed to select gas strategy: %v", err)
+    }
+
+    backend, err := rpcpool.NewPool(ctx, rpcpool.PoolConfig{Endpoints: profile.Endpoints})
+    if err != nil {
+        log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+    }
+    defer backend.Close()
+
+    address := profile.Contract()
+    if !*noDeploy {
+        if *artifactPath == "" {
+            *artifactPath = networks.ArtifactPath(profile)
+        }
+        artifact, err := bindings.ReadArtifact(*artifactPath)
+        if err != nil {
+            log.Fatalf("Failed to load artifact: %v", err)
+        }
+
+        var block uint64
+        address, block, err = deployContract(ctx, backend, deployer, profile.ChainIDBig(), strategy, artifact, *confirmations)
+        if err != nil {
+            log.Fatalf("Failed to deploy contract: %v", err)
+        }
+
+        if err := networks.SetDeployment(profile.Name, address, block); err != nil {
+            log.Fatalf("Deployed %s in block %d but failed to update %s: %v", address.Hex(), block, *configPath, err)
+        }
+        log.Printf("Network %s now uses %s (deployed in block %d)", profile.Name, address.Hex(), block)
+    }
+
+    if source == nil {
+        return
+    }
+    if err := migrateRecords(ctx, backend, deployer, profile.ChainIDBig(), strategy, *source, address); err != nil {
+        log.Fatalf("Failed to migrate records from %s: %v; resume with -no-deploy -migrate-from %s",
+            source.Hex(), err, source.Hex())
+    }
+}
+
+// deployContract sends the artifact's creation transaction and waits until
+// it has the requested number of confirmations
+func deployContract(ctx context.Context, backend *rpcpool.Pool, s signer.Signer, chainID *big.Int, strategy txmgr.GasStrategy, artifact *bindings.Artifact, confirmations uint64) (common.Address, uint64, error) {
+    fees, err := strategy.Fees(ctx, backend)
+    if err != nil {
+        return common.Address{}, 0, fmt.Errorf("failed to price transaction: %v", err)
+    }
+    opts := signer.TransactOpts(ctx, s, chainID)
+    fees.Apply(opts)
+
+    address, tx, err := artifact.Deploy(opts, backend)
+    if err != nil {
+        return common.Address{}, 0, err
+    }
+    log.Printf("Deploying %s from %s in %s", artifact.ContractName, s.Address().Hex(), tx.Hash().Hex())
+
+    receipt, err := bind.WaitMined(ctx, backend, tx)
+    if err != nil {
+        return common.Address{}, 0, fmt.Errorf("failed to get transaction receipt: %v", err)
+    }
+    if receipt.Status != types.ReceiptStatusSuccessful {
+        return common.Address{}, 0, fmt.Errorf("deployment reverted in block %d", receipt.BlockNumber.Uint64())
+    }
+
+    block := receipt.BlockNumber.Uint64()
+    for confirmations > 1 {
+        head, err := backend.BlockNumber(ctx)
+        if err != nil {
+            return common.Address{}, 0, fmt.Errorf("failed to retrieve head block: %v", err)
+        }
+        if head+1 >= block+confirmations {
+            break
+        }
+        select {
+        case <-time.After(2 * time.Second):
+        case <-ctx.Done():
+            return common.Address{}, 0, ctx.Err()
+        }
+    }
+
+    // Make sure the block we waited on still holds the deployment
+    code, err := backend.CodeAt(ctx, address, nil)
+    if err != nil {
+        return common.Address{}, 0, fmt.Errorf("failed to read code at %s: %v", address.Hex(), err)
+    }
+    if len(code) == 0 {
+        return common.Address{}, 0, fmt.Errorf("no code at %s after deployment", address.Hex())
+    }
+    return address, block, nil
+}
+
+// migrateRecords re-adds every record of the source contract to target.
+// The contract starts each record at phase 0 and only accepts future
+// release times, so records already released or about to be are skipped,
+// as are records target already holds; the command can be rerun safely.
+func migrateRecords(ctx context.Context, backend *rpcpool.Pool, s signer.Signer, chainID *big.Int, strategy txmgr.GasStrategy, from, to common.Address) error {
+    source, err := bindings.NewTwoPhaseCommit(from, backend)
+    if err != nil {
+        return fmt.Errorf("failed to bind source contract: %v", err)
+    }
+    target, err := bindings.NewTwoPhaseCommit(to, backend)
+    if err != nil {
+        return fmt.Errorf("failed to bind target contract: %v", err)
+    }
+
+    stored, err := source.ReturnStoredData(&bind.CallOpts{Context: ctx})
+    if err != nil {
+        return fmt.Errorf("failed to call returnStoredData: %v", err)
+    }
+    header, err := backend.HeaderByNumber(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("failed to fetch latest header: %v", err)
+    }
+    nonces, err := txmgr.NewNonceManager(ctx, backend, s.Address())
+    if err != nil {
+        return fmt.Errorf("failed to set up nonce manager: %v", err)
+    }
+    log.Printf("Migrating %d records from %s to %s", len(stored), from.Hex(), to.Hex())
+
+    var sent []*types.Transaction
+    var skipped int
+    for _, data := range stored {
+        name := data.Owner + "/" + data.DataName
+        if data.ReleaseTime.Uint64() <= header.Time+migrationMargin {
+            log.Printf("Skipping %s: release time %d has passed", name, data.ReleaseTime.Uint64())
+            skipped++
+            continue
+        }
+        _, _, _, _, releaseTime, _, err := target.GetPublicData(&bind.CallOpts{Context: ctx}, data.DataName, data.Owner)
+        if err != nil {
+            return fmt.Errorf("failed to look up %s on target: %v", name, err)
+        }
+        if releaseTime.Sign() != 0 {
+            log.Printf("Skipping %s: already migrated", name)
+            skipped++
+            continue
+        }
+        if data.KeyReleased {
+            log.Printf("Warning: the key for %s was released early on %s; it starts unreleased on %s", name, from.Hex(), to.Hex())
+        }
+
+        tx, err := sendMigration(ctx, backend, s, chainID, strategy, nonces, target, data)
+        if err != nil {
+            return fmt.Errorf("failed to migrate %s: %v", name, err)
+        }
+        log.Printf("Migrating %s in %s", name, tx.Hash().Hex())
+        sent = append(sent, tx)
+    }
+
+    var reverted int
+    for _, tx := range sent {
+        receipt, err := bind.WaitMined(ctx, backend, tx)
+        if err != nil {
+            return fmt.Errorf("failed to get receipt for %s: %v", tx.Hash().Hex(), err)
+        }
+        if receipt.Status != types.ReceiptStatusSuccessful {
+            log.Printf("Migration transaction %s reverted in block %d", tx.Hash().Hex(), receipt.BlockNumber.Uint64())
+            reverted++
+        }
+    }
+
+    log.Printf("Migrated %d records, skipped %d, %d reverted", len(sent)-reverted, skipped, reverted)
+    if reverted > 0 {
+        return fmt.Errorf("%d migration transactions reverted", reverted)
+    }
+    return nil
+}
+
+// sendMigration sends addStoredData for one record with the next nonce, so
+// migrations are pipelined instead of waiting for each to be mined
+func sendMigration(ctx context.Context, backend *rpcpool.Pool, s signer.Signer, chainID *big.Int, strategy txmgr.GasStrategy, nonces *txmgr.NonceManager, target *bindings.TwoPhaseCommit, data bindings.TwoPhaseCommitStoredData) (*types.Transaction, error) {
+    fees, err := strategy.Fees(ctx, backend)
+    if err != nil {
+        return nil, fmt.Errorf("failed to price transaction: %v", err)
+    }
+    opts := signer.TransactOpts(ctx, s, chainID)
+    fees.Apply(opts)
+
+    addStoredData := func(opts *bind.TransactOpts) (*types.Transaction, error) {
+        return target.AddStoredData(opts, data.EncryptedData, data.Owner, data.DataName, data.ReleaseTime, data.Hash)
+    }
+
+    gasLimit, err := bindings.EstimateGas(opts, addStoredData)
+    if err != nil {
+        return nil, fmt.Errorf("failed to estimate gas limit: %v", err)
+    }
+    opts.GasLimit = uint64(float64(gasLimit) * 1.1)
+
+    nonce, err := nonces.Next(ctx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to reserve account nonce: %v", err)
+    }
+    opts.Nonce = new(big.Int).SetUint64(nonce)
+
+    tx, err := addStoredData(opts)
+    if err != nil {
+        nonces.Failed(ctx, nonce, err)
+        return nil, fmt.Errorf("failed to send transaction: %v", err)
+    }
+    nonces.Sent(nonce)
+    return tx, nil
+}
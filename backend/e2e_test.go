@@ -0,0 +1,316 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    apiclient "web3server/client"
+    h "web3server/helper"
+    "web3server/simchain"
+
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/crypto"
+)
+
+// e2eServerEnv makes the test binary run the server's main instead of the
+// tests, so TestEndToEnd can start the real backend as a child process
+const e2eServerEnv = "WEB3SERVER_E2E_SERVER"
+
+func TestMain(m *testing.M) {
+    if os.Getenv(e2eServerEnv) == "1" {
+        main()
+        return
+    }
+    os.Exit(m.Run())
+}
+
+// freePort returns a local TCP port nothing is listening on
+func freePort(t *testing.T) int {
+    t.Helper()
+
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer listener.Close()
+    return listener.Addr().(*net.TCPAddr).Port
+}
+
+// TestEndToEnd runs the backend against a simulated chain with its own
+// keeper and takes a record through upload, phase 1, key release and
+// decryption over the HTTP API.
+func TestEndToEnd(t *testing.T) {
+    if testing.Short() {
+        t.Skip("starts a chain and the server")
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+    defer cancel()
+
+    key, err := crypto.GenerateKey()
+    if err != nil {
+        t.Fatal(err)
+    }
+    serverKey := hex.EncodeToString(crypto.FromECDSA(key))
+    chain, err := simchain.NewChain(simchain.ChainConfig{
+        WSPort:    freePort(t),
+        Fund:      []common.Address{crypto.PubkeyToAddress(key.PublicKey)},
+        BlockTime: 200 * time.Millisecond,
+        Keeper:    true,
+    })
+    if err != nil {
+        t.Fatalf("failed to start simulated chain: %v", err)
+    }
+    defer chain.Close()
+    if _, err := chain.Deploy(ctx, "TwoPhaseCommit.json"); err != nil {
+        t.Fatalf("failed to deploy contract: %v", err)
+    }
+    chain.Start(ctx)
+    sim := httptest.NewServer(chain.AdminHandler())
+    defer sim.Close()
+
+    api := startE2EServer(t, ctx, chain, serverKey)
+    if err := e2eLifecycle(ctx, t, api, sim.URL); err != nil {
+        t.Fatal(err)
+    }
+}
+
+// startE2EServer runs the backend in a temporary directory against chain
+// and waits until it serves requests
+func startE2EServer(t *testing.T, ctx context.Context, chain *simchain.Chain, privateKey string) string {
+    t.Helper()
+    dir := t.TempDir()
+
+    artifact, err := filepath.Abs("TwoPhaseCommit.json")
+    if err != nil {
+        t.Fatal(err)
+    }
+    networks := fmt.Sprintf(`defaultNetwork: simulated
+networks:
+  simulated:
+    endpoints:
+      - %s
+    chainId: %d
+    contractAddress: "%s"
+    artifact: %s
+    deploymentBlock: %d
+`, chain.Endpoint(), simchain.ChainID, chain.Address.Hex(), artifact, chain.Deployed)
+    if err := os.WriteFile(filepath.Join(dir, "networks.yaml"), []byte(networks), 0600); err != nil {
+        t.Fatal(err)
+    }
+
+    masterKey := make([]byte, 32)
+    if _, err := rand.Read(masterKey); err != nil {
+        t.Fatal(err)
+    }
+    port := freePort(t)
+    env := []string{
+        "PRIVATE_KEY=" + privateKey,
+        "KEYSTORE_MASTER_KEY=" + hex.EncodeToString(masterKey),
+        "NETWORK=simulated",
+        "NETWORK_CONFIG=" + filepath.Join(dir, "networks.yaml"),
+        "JOB_CONFIRMATIONS=2",
+        "EVENT_CONFIRMATIONS=2",
+        fmt.Sprintf("PORT=%d", port),
+        fmt.Sprintf("BLOB_PUBLIC_URL=http://127.0.0.1:%d", port),
+        "GIN_MODE=release",
+    }
+    // main refuses to start without a .env file, so write the same settings there
+    if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(strings.Join(env, "\n")+"\n"), 0600); err != nil {
+        t.Fatal(err)
+    }
+
+    var output bytes.Buffer
+    server := exec.Command(os.Args[0], "-test.run=^$")
+    server.Dir = dir
+    server.Env = append(append(os.Environ(), env...), e2eServerEnv+"=1")
+    server.Stdout, server.Stderr = &output, &output
+    if err := server.Start(); err != nil {
+        t.Fatalf("failed to start server: %v", err)
+    }
+    t.Cleanup(func() {
+        server.Process.Kill()
+        server.Wait()
+        if t.Failed() {
+            t.Logf("server output:\n%s", output.String())
+        }
+    })
+
+    api := fmt.Sprintf("http://127.0.0.1:%d", port)
+    err = e2ePoll(ctx, "the server to start", func() (bool, error) {
+        err := e2eCall(ctx, http.MethodGet, api+"/events/status", nil)
+        return err == nil, err
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    return api
+}
+
+func e2eLifecycle(ctx context.Context, t *testing.T, api, sim string) error {
+    backend := apiclient.NewClient(api)
+    owner := "0xE2EOwner"
+    dataName := fmt.Sprintf("e2e-%d", time.Now().UnixNano())
+    plaintext := "end-to-end " + dataName
+
+    var head struct {
+        Timestamp uint64 `json:"timestamp"`
+    }
+    if err := e2eCall(ctx, http.MethodGet, sim+"/head", &head); err != nil {
+        return fmt.Errorf("failed to read chain head: %w", err)
+    }
+    // Phase 1 fires 12 hours before release; leave an hour before that
+    releaseTime := head.Timestamp + 43200 + 3600
+
+    sealed, err := apiclient.Seal(h.DefaultCipher(), plaintext)
+    if err != nil {
+        return err
+    }
+    upload, err := backend.Upload(ctx, sealed, owner, dataName, releaseTime, true)
+    if err != nil {
+        return fmt.Errorf("upload failed: %w", err)
+    }
+    t.Logf("Uploaded %s/%s as job %s (%s)", owner, dataName, upload.JobID, upload.TransactionHash)
+
+    job, err := backend.WaitJob(ctx, upload.JobID, time.Second)
+    if err != nil {
+        return fmt.Errorf("upload job did not settle: %w", err)
+    }
+    if job.Status != "confirmed" {
+        return fmt.Errorf("upload job ended %s: %s", job.Status, job.Error)
+    }
+    t.Logf("Upload confirmed in block %d", job.BlockNumber)
+
+    if err := e2eAdvance(ctx, t, sim, 3600+60); err != nil {
+        return err
+    }
+    phaseOne := fmt.Sprintf("%s/events?name=ReleaseEncryptedData&owner=%s&dataname=%s",
+        api, url.QueryEscape(owner), url.QueryEscape(dataName))
+    err = e2ePoll(ctx, "phase 1", func() (bool, error) {
+        var events []json.RawMessage
+        err := e2eCall(ctx, http.MethodGet, phaseOne, &events)
+        return len(events) > 0, err
+    })
+    if err != nil {
+        return err
+    }
+    t.Logf("Phase 1 emitted ReleaseEncryptedData")
+
+    decryptURL := fmt.Sprintf("%s/decrypt/%s/%s", api, url.PathEscape(dataName), url.PathEscape(owner))
+    if err := e2eCall(ctx, http.MethodGet, decryptURL, nil); !isStatus(err, http.StatusLocked) {
+        return fmt.Errorf("decrypt before the release time returned %v, want status %d", err, http.StatusLocked)
+    }
+
+    if err := e2eAdvance(ctx, t, sim, 43200); err != nil {
+        return err
+    }
+    releaseURL := fmt.Sprintf("%s/releases/%s/%s", api, url.PathEscape(dataName), url.PathEscape(owner))
+    err = e2ePoll(ctx, "key release", func() (bool, error) {
+        var record struct {
+            Status string `json:"status"`
+        }
+        err := e2eCall(ctx, http.MethodGet, releaseURL, &record)
+        return record.Status == "released" || record.Status == "confirmed", err
+    })
+    if err != nil {
+        return err
+    }
+    t.Logf("Release key published")
+
+    var decrypted struct {
+        Data      string `json:"data"`
+        KeySource string `json:"keySource"`
+    }
+    err = e2ePoll(ctx, "decryption", func() (bool, error) {
+        err := e2eCall(ctx, http.MethodGet, decryptURL, &decrypted)
+        return err == nil, err
+    })
+    if err != nil {
+        return fmt.Errorf("decrypt failed: %w", err)
+    }
+    if decrypted.Data != plaintext {
+        return fmt.Errorf("decrypted %q, want %q", decrypted.Data, plaintext)
+    }
+    t.Logf("Decrypted with the key from %s", decrypted.KeySource)
+    return nil
+}
+
+// e2eAdvance moves the simulated chain clock forward
+func e2eAdvance(ctx context.Context, t *testing.T, sim string, seconds uint64) error {
+    err := e2eCall(ctx, http.MethodPost, fmt.Sprintf("%s/advance?seconds=%d", sim, seconds), nil)
+    if err != nil {
+        return fmt.Errorf("failed to advance chain time: %w", err)
+    }
+    t.Logf("Advanced chain time by %ds", seconds)
+    return nil
+}
+
+// e2ePoll retries check every 200ms until it reports done or ctx expires
+func e2ePoll(ctx context.Context, what string, check func() (bool, error)) error {
+    ticker := time.NewTicker(200 * time.Millisecond)
+    defer ticker.Stop()
+
+    var lastErr error
+    for {
+        done, err := check()
+        if done {
+            return nil
+        }
+        lastErr = err
+
+        select {
+        case <-ticker.C:
+        case <-ctx.Done():
+            return fmt.Errorf("timed out waiting for %s (last error: %v)", what, lastErr)
+        }
+    }
+}
+
+// statusError is a non-2xx response from e2eCall
+type statusError struct {
+    method, target string
+    code           int
+}
+
+func (e *statusError) Error() string {
+    return fmt.Sprintf("%s %s returned %d", e.method, e.target, e.code)
+}
+
+func isStatus(err error, code int) bool {
+    var status *statusError
+    return errors.As(err, &status) && status.code == code
+}
+
+func e2eCall(ctx context.Context, method, target string, out interface{}) error {
+    req, err := http.NewRequestWithContext(ctx, method, target, nil)
+    if err != nil {
+        return err
+    }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return &statusError{method: method, target: target, code: resp.StatusCode}
+    }
+    if out == nil {
+        return nil
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,40 @@
+package helper
+
+import (
+    "crypto/rand"
+
+    "github.com/ethereum/go-ethereum/crypto"
+    "github.com/ethereum/go-ethereum/crypto/ecies"
+)
+
+// ECIES wraps data keys with ECIES over secp256k1 using go-ethereum's crypto.
+// Release keys are raw 32 byte scalars.
+type ECIES struct{}
+
+func (ECIES) ID() CipherID { return CipherECIES }
+
+func (ECIES) Name() string { return "ecies-secp256k1" }
+
+func (ECIES) GenerateKey() ([]byte, []byte, error) {
+    privKey, err := crypto.GenerateKey()
+    if err != nil {
+        return nil, nil, err
+    }
+    return crypto.FromECDSA(privKey), crypto.FromECDSAPub(&privKey.PublicKey), nil
+}
+
+func (ECIES) WrapKey(publicKey []byte, dataKey []byte) ([]byte, error) {
+    pubKey, err := crypto.UnmarshalPubkey(publicKey)
+    if err != nil {
+        return nil, err
+    }
+    return ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(pubKey), dataKey, nil, nil)
+}
+
+func (ECIES) UnwrapKey(privateKey []byte, wrappedKey []byte) ([]byte, error) {
+    privKey, err := crypto.ToECDSA(privateKey)
+    if err != nil {
+        return nil, err
+    }
+    return ecies.ImportECDSA(privKey).Decrypt(wrappedKey, nil, nil)
+}
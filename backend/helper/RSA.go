@@ -10,23 +10,52 @@ import (
 
 
 
-func EncryptData(data string) ([]byte, []byte, error){
+// RSAOAEP wraps data keys with RSA-2048 OAEP. Release keys are PKCS#1 encoded.
+type RSAOAEP struct{}
+
+func (RSAOAEP) ID() CipherID { return CipherRSAOAEP }
+
+func (RSAOAEP) Name() string { return "rsa-oaep" }
+
+func (RSAOAEP) GenerateKey() ([]byte, []byte, error) {
     privKey, err := rsa.GenerateKey(rand.Reader, 2048)
     if err != nil {
-        return []byte{}, []byte{}, err
+        return nil, nil, err
     }
+    return x509.MarshalPKCS1PrivateKey(privKey), x509.MarshalPKCS1PublicKey(&privKey.PublicKey), nil
+}
 
-    privBytes := x509.MarshalPKCS1PrivateKey(privKey)
+func (RSAOAEP) WrapKey(publicKey []byte, dataKey []byte) ([]byte, error) {
+    pubKey, err := x509.ParsePKCS1PublicKey(publicKey)
+    if err != nil {
+        return nil, err
+    }
+    return rsa.EncryptOAEP(sha256.New(), rand.Reader, pubKey, dataKey, nil)
+}
 
-    encryptedData, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &privKey.PublicKey, []byte(data), nil)
+func (RSAOAEP) UnwrapKey(privateKey []byte, wrappedKey []byte) ([]byte, error) {
+    privKey, err := x509.ParsePKCS1PrivateKey(privateKey)
+    if err != nil {
+        return nil, err
+    }
+    return rsa.DecryptOAEP(sha256.New(), rand.Reader, privKey, wrappedKey, nil)
+}
+
+
+// isLegacyRSA reports whether data looks like a pre-envelope ciphertext,
+// which is exactly one RSA block long.
+func isLegacyRSA(data []byte, key []byte) bool {
+    privKey, err := x509.ParsePKCS1PrivateKey(key)
     if err != nil {
-        return []byte{}, []byte{}, err
+        return false
     }
-    return encryptedData, privBytes, nil
+    return len(data) == privKey.Size()
 }
 
 
-func DecryptData(data []byte, key []byte) (string, error){
+// decryptLegacyRSA opens ciphertexts where the plaintext was RSA-OAEP
+// encrypted directly.
+func decryptLegacyRSA(data []byte, key []byte) (string, error){
     privKey, err := x509.ParsePKCS1PrivateKey(key)
     if err != nil {
         return "", err
@@ -0,0 +1,96 @@
+package helper
+
+import (
+    "crypto/cipher"
+    "crypto/rand"
+    "crypto/sha256"
+    "errors"
+    "io"
+
+    "golang.org/x/crypto/chacha20poly1305"
+    "golang.org/x/crypto/curve25519"
+    "golang.org/x/crypto/hkdf"
+)
+
+// X25519 wraps data keys with an ephemeral X25519 exchange and
+// XChaCha20-Poly1305. Release keys are raw 32 byte scalars.
+type X25519 struct{}
+
+func (X25519) ID() CipherID { return CipherX25519 }
+
+func (X25519) Name() string { return "x25519-xchacha20poly1305" }
+
+func (X25519) GenerateKey() ([]byte, []byte, error) {
+    privKey := make([]byte, curve25519.ScalarSize)
+    if _, err := rand.Read(privKey); err != nil {
+        return nil, nil, err
+    }
+    pubKey, err := curve25519.X25519(privKey, curve25519.Basepoint)
+    if err != nil {
+        return nil, nil, err
+    }
+    return privKey, pubKey, nil
+}
+
+// WrapKey outputs the ephemeral public key, the nonce and the sealed data key
+func (X25519) WrapKey(publicKey []byte, dataKey []byte) ([]byte, error) {
+    ephemeral := make([]byte, curve25519.ScalarSize)
+    if _, err := rand.Read(ephemeral); err != nil {
+        return nil, err
+    }
+    ephemeralPub, err := curve25519.X25519(ephemeral, curve25519.Basepoint)
+    if err != nil {
+        return nil, err
+    }
+    shared, err := curve25519.X25519(ephemeral, publicKey)
+    if err != nil {
+        return nil, err
+    }
+
+    aead, err := x25519AEAD(shared, ephemeralPub, publicKey)
+    if err != nil {
+        return nil, err
+    }
+    nonce := make([]byte, aead.NonceSize())
+    if _, err := rand.Read(nonce); err != nil {
+        return nil, err
+    }
+
+    out := append(append([]byte{}, ephemeralPub...), nonce...)
+    return aead.Seal(out, nonce, dataKey, nil), nil
+}
+
+func (X25519) UnwrapKey(privateKey []byte, wrappedKey []byte) ([]byte, error) {
+    if len(wrappedKey) < curve25519.PointSize+chacha20poly1305.NonceSizeX {
+        return nil, errors.New("wrapped key too short")
+    }
+    ephemeralPub := wrappedKey[:curve25519.PointSize]
+    nonce := wrappedKey[curve25519.PointSize : curve25519.PointSize+chacha20poly1305.NonceSizeX]
+    sealed := wrappedKey[curve25519.PointSize+chacha20poly1305.NonceSizeX:]
+
+    publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+    if err != nil {
+        return nil, err
+    }
+    shared, err := curve25519.X25519(privateKey, ephemeralPub)
+    if err != nil {
+        return nil, err
+    }
+
+    aead, err := x25519AEAD(shared, ephemeralPub, publicKey)
+    if err != nil {
+        return nil, err
+    }
+    return aead.Open(nil, nonce, sealed, nil)
+}
+
+// x25519AEAD derives the key encryption key from the shared secret, bound
+// to both public keys.
+func x25519AEAD(shared, ephemeralPub, publicKey []byte) (cipher.AEAD, error) {
+    salt := append(append([]byte{}, ephemeralPub...), publicKey...)
+    kek := make([]byte, chacha20poly1305.KeySize)
+    if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte("tpc-x25519-wrap")), kek); err != nil {
+        return nil, err
+    }
+    return chacha20poly1305.NewX(kek)
+}
@@ -0,0 +1,71 @@
+package helper
+
+import (
+    "fmt"
+    "sort"
+)
+
+// CipherID identifies a cipher suite inside the ciphertext header
+type CipherID byte
+
+const (
+    CipherRSAOAEP CipherID = 1
+    CipherECIES   CipherID = 2
+    CipherX25519  CipherID = 3
+)
+
+// Cipher wraps the envelope data key under an asymmetric release key.
+// The private key is what gets published through releaseKey.
+type Cipher interface {
+    ID() CipherID
+    Name() string
+    GenerateKey() (privateKey []byte, publicKey []byte, err error)
+    WrapKey(publicKey []byte, dataKey []byte) ([]byte, error)
+    UnwrapKey(privateKey []byte, wrappedKey []byte) ([]byte, error)
+}
+
+var ciphers = map[CipherID]Cipher{}
+
+func registerCipher(c Cipher) {
+    ciphers[c.ID()] = c
+}
+
+func init() {
+    registerCipher(RSAOAEP{})
+    registerCipher(ECIES{})
+    registerCipher(X25519{})
+}
+
+// DefaultCipher is used when an upload does not pick a suite
+func DefaultCipher() Cipher {
+    return RSAOAEP{}
+}
+
+// CipherByID returns the suite recorded in a ciphertext header
+func CipherByID(id CipherID) (Cipher, error) {
+    c, exists := ciphers[id]
+    if !exists {
+        return nil, fmt.Errorf("unknown cipher id %d", id)
+    }
+    return c, nil
+}
+
+// CipherByName returns the suite with the given name, e.g. "ecies-secp256k1"
+func CipherByName(name string) (Cipher, error) {
+    for _, c := range ciphers {
+        if c.Name() == name {
+            return c, nil
+        }
+    }
+    return nil, fmt.Errorf("unknown cipher %q, expected one of %v", name, CipherNames())
+}
+
+// CipherNames lists the registered suite names
+func CipherNames() []string {
+    names := make([]string, 0, len(ciphers))
+    for _, c := range ciphers {
+        names = append(names, c.Name())
+    }
+    sort.Strings(names)
+    return names
+}
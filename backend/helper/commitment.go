@@ -0,0 +1,51 @@
+package helper
+
+import (
+    "crypto/sha256"
+    "crypto/subtle"
+)
+
+// The on-chain hash field holds sha256(ciphertext) followed by a commitment
+// to the release key. Records uploaded before commitments existed carry only
+// the 32 byte ciphertext hash.
+const (
+    ciphertextHashSize = sha256.Size
+    commitmentSize     = sha256.Size
+)
+
+var commitmentDomain = []byte("TwoPhaseCommit key commitment")
+
+// KeyCommitment binds a release key without revealing it
+func KeyCommitment(privateKey []byte) []byte {
+    digest := sha256.New()
+    digest.Write(commitmentDomain)
+    digest.Write(privateKey)
+    return digest.Sum(nil)
+}
+
+// VerifyKeyCommitment reports whether privateKey opens the commitment
+func VerifyKeyCommitment(commitment []byte, privateKey []byte) bool {
+    return subtle.ConstantTimeCompare(commitment, KeyCommitment(privateKey)) == 1
+}
+
+// RecordHash builds the value stored in the contract's hash field
+func RecordHash(encryptedData []byte, commitment []byte) []byte {
+    hash := sha256.Sum256(encryptedData)
+    return append(hash[:], commitment...)
+}
+
+// SplitRecordHash separates the ciphertext hash from the key commitment,
+// which is nil for records without one.
+func SplitRecordHash(hash []byte) ([]byte, []byte) {
+    if len(hash) == ciphertextHashSize+commitmentSize {
+        return hash[:ciphertextHashSize], hash[ciphertextHashSize:]
+    }
+    return hash, nil
+}
+
+// VerifyCiphertextHash checks the ciphertext against the on-chain hash field
+func VerifyCiphertextHash(encryptedData []byte, hash []byte) bool {
+    ciphertextHash, _ := SplitRecordHash(hash)
+    expected := sha256.Sum256(encryptedData)
+    return subtle.ConstantTimeCompare(expected[:], ciphertextHash) == 1
+}
@@ -0,0 +1,288 @@
+package helper
+
+import (
+    "bytes"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "encoding/binary"
+    "errors"
+    "fmt"
+)
+
+// Envelope ciphertexts start with a magic prefix and a version byte so the
+// format can evolve while legacy RSA-only ciphertexts stay readable.
+// Version 1 implies RSA-OAEP; version 2 adds a cipher suite byte and
+// version 3 also carries an RSW puzzle that locks the data key.
+var envelopeMagic = []byte("TPC")
+
+const (
+    EnvelopeVersion1 byte = 1
+    EnvelopeVersion2 byte = 2
+    EnvelopeVersion3 byte = 3
+
+    dataKeySize   = 32
+    keyLenSize    = 2
+    puzzleLenSize = 4
+)
+
+var ErrMalformedEnvelope = errors.New("malformed ciphertext envelope")
+
+// IsEnvelope reports whether the ciphertext carries a versioned envelope header
+func IsEnvelope(data []byte) bool {
+    return len(data) > len(envelopeMagic) && bytes.Equal(data[:len(envelopeMagic)], envelopeMagic)
+}
+
+// EncryptData encrypts data with the default cipher suite
+func EncryptData(data string) ([]byte, []byte, error){
+    return EncryptDataWith(DefaultCipher(), data)
+}
+
+// EncryptDataWith seals data under a fresh release key of the given suite.
+// It returns the envelope and the private key that later releases it.
+func EncryptDataWith(c Cipher, data string) ([]byte, []byte, error) {
+    privKey, pubKey, err := c.GenerateKey()
+    if err != nil {
+        return nil, nil, err
+    }
+
+    encryptedData, err := SealEnvelope(c, pubKey, []byte(data))
+    if err != nil {
+        return nil, nil, err
+    }
+    return encryptedData, privKey, nil
+}
+
+// EncryptDataTimeLocked works like EncryptDataWith but also locks the data
+// key in a time-lock puzzle, so anyone can decrypt after the given number
+// of sequential squarings without waiting for the release key.
+func EncryptDataTimeLocked(c Cipher, data string, squarings uint64) ([]byte, []byte, error) {
+    privKey, pubKey, err := c.GenerateKey()
+    if err != nil {
+        return nil, nil, err
+    }
+
+    encryptedData, err := sealEnvelope(c, pubKey, []byte(data), squarings)
+    if err != nil {
+        return nil, nil, err
+    }
+    return encryptedData, privKey, nil
+}
+
+// DecryptData opens envelope ciphertexts of any suite as well as legacy ones
+// where the plaintext was RSA-OAEP encrypted directly.
+func DecryptData(data []byte, key []byte) (string, error){
+    if IsEnvelope(data) && !isLegacyRSA(data, key) {
+        decryptedData, err := OpenEnvelope(data, key)
+        if err != nil {
+            return "", err
+        }
+        return string(decryptedData), nil
+    }
+
+    return decryptLegacyRSA(data, key)
+}
+
+// EnvelopeCipher returns the suite recorded in an envelope header
+func EnvelopeCipher(data []byte) (Cipher, error) {
+    header, err := parseHeader(data)
+    if err != nil {
+        return nil, err
+    }
+    return header.cipher, nil
+}
+
+// EnvelopePuzzle returns the time-lock puzzle of an envelope, or nil if it
+// has none.
+func EnvelopePuzzle(data []byte) (*Puzzle, error) {
+    header, err := parseHeader(data)
+    if err != nil {
+        return nil, err
+    }
+    return header.puzzle, nil
+}
+
+// SealEnvelope encrypts the payload under a fresh AES-256-GCM data key and
+// wraps that data key for the suite's public key.
+func SealEnvelope(c Cipher, publicKey []byte, data []byte) ([]byte, error) {
+    return sealEnvelope(c, publicKey, data, 0)
+}
+
+// sealEnvelope additionally locks the data key in a puzzle when squarings
+// is non-zero.
+func sealEnvelope(c Cipher, publicKey []byte, data []byte, squarings uint64) ([]byte, error) {
+    dataKey := make([]byte, dataKeySize)
+    if _, err := rand.Read(dataKey); err != nil {
+        return nil, err
+    }
+
+    wrappedKey, err := c.WrapKey(publicKey, dataKey)
+    if err != nil {
+        return nil, fmt.Errorf("failed to wrap data key: %v", err)
+    }
+    if len(wrappedKey) > 0xffff {
+        return nil, fmt.Errorf("wrapped data key too large: %d bytes", len(wrappedKey))
+    }
+
+    header := append(append([]byte{}, envelopeMagic...), EnvelopeVersion2, byte(c.ID()))
+    if squarings > 0 {
+        puzzle, err := NewPuzzle(dataKey, squarings)
+        if err != nil {
+            return nil, fmt.Errorf("failed to create time-lock puzzle: %v", err)
+        }
+        encoded := puzzle.Marshal()
+        header = append(append([]byte{}, envelopeMagic...), EnvelopeVersion3, byte(c.ID()))
+        header = binary.BigEndian.AppendUint32(header, uint32(len(encoded)))
+        header = append(header, encoded...)
+    }
+
+    aead, err := newAEAD(dataKey)
+    if err != nil {
+        return nil, err
+    }
+    nonce := make([]byte, aead.NonceSize())
+    if _, err := rand.Read(nonce); err != nil {
+        return nil, err
+    }
+
+    out := make([]byte, 0, len(header)+keyLenSize+len(wrappedKey)+len(nonce)+len(data)+aead.Overhead())
+    out = append(out, header...)
+    out = binary.BigEndian.AppendUint16(out, uint16(len(wrappedKey)))
+    out = append(out, wrappedKey...)
+    out = append(out, nonce...)
+    // The header is authenticated so the version, suite and puzzle cannot be swapped
+    out = aead.Seal(out, nonce, data, header)
+
+    return out, nil
+}
+
+// OpenEnvelope reverses SealEnvelope with the released private key
+func OpenEnvelope(data []byte, privateKey []byte) ([]byte, error) {
+    header, err := parseHeader(data)
+    if err != nil {
+        return nil, err
+    }
+
+    rest := data[len(header.raw):]
+    if len(rest) < keyLenSize {
+        return nil, ErrMalformedEnvelope
+    }
+    keyLen := int(binary.BigEndian.Uint16(rest))
+    rest = rest[keyLenSize:]
+    if len(rest) < keyLen {
+        return nil, ErrMalformedEnvelope
+    }
+    wrappedKey, rest := rest[:keyLen], rest[keyLen:]
+
+    dataKey, err := header.cipher.UnwrapKey(privateKey, wrappedKey)
+    if err != nil {
+        return nil, fmt.Errorf("failed to unwrap data key: %v", err)
+    }
+
+    return openPayload(header, rest, dataKey)
+}
+
+// SolveEnvelope opens a time-locked envelope by solving its puzzle, without
+// needing the release key.
+func SolveEnvelope(data []byte) ([]byte, error) {
+    header, err := parseHeader(data)
+    if err != nil {
+        return nil, err
+    }
+    if header.puzzle == nil {
+        return nil, errors.New("envelope has no time-lock puzzle")
+    }
+
+    rest, err := skipWrappedKey(data[len(header.raw):])
+    if err != nil {
+        return nil, err
+    }
+
+    dataKey, err := header.puzzle.Solve()
+    if err != nil {
+        return nil, err
+    }
+
+    return openPayload(header, rest, dataKey)
+}
+
+func skipWrappedKey(rest []byte) ([]byte, error) {
+    if len(rest) < keyLenSize {
+        return nil, ErrMalformedEnvelope
+    }
+    keyLen := int(binary.BigEndian.Uint16(rest))
+    rest = rest[keyLenSize:]
+    if len(rest) < keyLen {
+        return nil, ErrMalformedEnvelope
+    }
+    return rest[keyLen:], nil
+}
+
+// openPayload decrypts the nonce and sealed payload following the wrapped key
+func openPayload(header envelopeHeader, rest []byte, dataKey []byte) ([]byte, error) {
+    aead, err := newAEAD(dataKey)
+    if err != nil {
+        return nil, err
+    }
+    if len(rest) < aead.NonceSize() {
+        return nil, ErrMalformedEnvelope
+    }
+    nonce, sealed := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+
+    return aead.Open(nil, nonce, sealed, header.raw)
+}
+
+type envelopeHeader struct {
+    raw    []byte
+    cipher Cipher
+    puzzle *Puzzle
+}
+
+func parseHeader(data []byte) (envelopeHeader, error) {
+    if !IsEnvelope(data) {
+        return envelopeHeader{}, ErrMalformedEnvelope
+    }
+
+    version := data[len(envelopeMagic)]
+    switch version {
+    case EnvelopeVersion1:
+        return envelopeHeader{raw: data[:len(envelopeMagic)+1], cipher: RSAOAEP{}}, nil
+    case EnvelopeVersion2:
+        if len(data) < len(envelopeMagic)+2 {
+            return envelopeHeader{}, ErrMalformedEnvelope
+        }
+        c, err := CipherByID(CipherID(data[len(envelopeMagic)+1]))
+        if err != nil {
+            return envelopeHeader{}, err
+        }
+        return envelopeHeader{raw: data[:len(envelopeMagic)+2], cipher: c}, nil
+    case EnvelopeVersion3:
+        prefix := len(envelopeMagic) + 2 + puzzleLenSize
+        if len(data) < prefix {
+            return envelopeHeader{}, ErrMalformedEnvelope
+        }
+        c, err := CipherByID(CipherID(data[len(envelopeMagic)+1]))
+        if err != nil {
+            return envelopeHeader{}, err
+        }
+        puzzleLen := int(binary.BigEndian.Uint32(data[len(envelopeMagic)+2:]))
+        if len(data)-prefix < puzzleLen {
+            return envelopeHeader{}, ErrMalformedEnvelope
+        }
+        puzzle, err := UnmarshalPuzzle(data[prefix : prefix+puzzleLen])
+        if err != nil {
+            return envelopeHeader{}, err
+        }
+        return envelopeHeader{raw: data[:prefix+puzzleLen], cipher: c, puzzle: puzzle}, nil
+    default:
+        return envelopeHeader{}, fmt.Errorf("unsupported envelope version %d", version)
+    }
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    return cipher.NewGCM(block)
+}
@@ -0,0 +1,139 @@
+package helper
+
+import (
+    "crypto/rand"
+    "errors"
+    "fmt"
+)
+
+// Share is one point of a Shamir polynomial over GF(256). Y holds one byte
+// per secret byte, all evaluated at the same X.
+type Share struct {
+    X byte
+    Y []byte
+}
+
+// Bytes encodes the share as X followed by Y
+func (s Share) Bytes() []byte {
+    return append([]byte{s.X}, s.Y...)
+}
+
+// ParseShare decodes a share produced by Bytes
+func ParseShare(data []byte) (Share, error) {
+    if len(data) < 2 || data[0] == 0 {
+        return Share{}, errors.New("malformed share")
+    }
+    return Share{X: data[0], Y: append([]byte{}, data[1:]...)}, nil
+}
+
+// SplitSecret splits secret into n shares, any threshold of which recover it
+func SplitSecret(secret []byte, n, threshold int) ([]Share, error) {
+    if threshold < 1 || threshold > n || n > 255 {
+        return nil, fmt.Errorf("invalid %d-of-%d sharing", threshold, n)
+    }
+    if len(secret) == 0 {
+        return nil, errors.New("cannot split an empty secret")
+    }
+
+    shares := make([]Share, n)
+    for i := range shares {
+        shares[i] = Share{X: byte(i + 1), Y: make([]byte, len(secret))}
+    }
+
+    // One random polynomial of degree threshold-1 per secret byte
+    coefficients := make([]byte, threshold)
+    for b, secretByte := range secret {
+        if _, err := rand.Read(coefficients[1:]); err != nil {
+            return nil, err
+        }
+        coefficients[0] = secretByte
+
+        for i := range shares {
+            shares[i].Y[b] = evalPolynomial(coefficients, shares[i].X)
+        }
+    }
+    return shares, nil
+}
+
+// CombineShares recovers the secret with Lagrange interpolation at zero
+func CombineShares(shares []Share) ([]byte, error) {
+    if len(shares) == 0 {
+        return nil, errors.New("no shares to combine")
+    }
+    size := len(shares[0].Y)
+    seen := make(map[byte]bool)
+    for _, share := range shares {
+        if share.X == 0 || seen[share.X] {
+            return nil, fmt.Errorf("duplicate or invalid share x=%d", share.X)
+        }
+        if len(share.Y) != size {
+            return nil, errors.New("shares have different lengths")
+        }
+        seen[share.X] = true
+    }
+
+    secret := make([]byte, size)
+    for i, share := range shares {
+        // Lagrange basis polynomial for share i evaluated at zero
+        basis := byte(1)
+        for j, other := range shares {
+            if i == j {
+                continue
+            }
+            basis = gfMul(basis, gfDiv(other.X, other.X^share.X))
+        }
+        for b := range secret {
+            secret[b] ^= gfMul(share.Y[b], basis)
+        }
+    }
+    return secret, nil
+}
+
+func evalPolynomial(coefficients []byte, x byte) byte {
+    // Horner's method, highest degree first
+    result := byte(0)
+    for i := len(coefficients) - 1; i >= 0; i-- {
+        result = gfMul(result, x) ^ coefficients[i]
+    }
+    return result
+}
+
+// GF(256) with the AES polynomial x^8 + x^4 + x^3 + x + 1
+var gfExp, gfLog = gfTables()
+
+func gfTables() ([512]byte, [256]byte) {
+    var exp [512]byte
+    var log [256]byte
+    x := byte(1)
+    for i := 0; i < 255; i++ {
+        exp[i] = x
+        log[x] = byte(i)
+        // Multiply by the generator 3
+        x ^= gfXtime(x)
+    }
+    for i := 255; i < 512; i++ {
+        exp[i] = exp[i-255]
+    }
+    return exp, log
+}
+
+func gfXtime(x byte) byte {
+    if x&0x80 != 0 {
+        return x<<1 ^ 0x1b
+    }
+    return x << 1
+}
+
+func gfMul(a, b byte) byte {
+    if a == 0 || b == 0 {
+        return 0
+    }
+    return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+    if a == 0 {
+        return 0
+    }
+    return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
@@ -0,0 +1,159 @@
+package helper
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "math/big"
+    "time"
+)
+
+// Puzzle is a Rivest-Shamir-Wagner time-lock puzzle. Recovering the locked
+// secret takes T sequential squarings of A modulo N; only the creator, who
+// knows the factors of N, can shortcut them.
+type Puzzle struct {
+    N         *big.Int
+    A         *big.Int
+    T         uint64
+    LockedKey []byte
+}
+
+const puzzleModulusBits = 2048
+
+var ErrMalformedPuzzle = errors.New("malformed time-lock puzzle")
+
+// CalibrateSquarings measures how many modular squarings per second this
+// machine performs for a puzzle sized modulus.
+func CalibrateSquarings(sample time.Duration) (uint64, error) {
+    n, _, err := puzzleModulus()
+    if err != nil {
+        return 0, err
+    }
+    x, err := rand.Int(rand.Reader, n)
+    if err != nil {
+        return 0, err
+    }
+
+    var count uint64
+    start := time.Now()
+    for time.Since(start) < sample {
+        for i := 0; i < 1000; i++ {
+            x.Mul(x, x).Mod(x, n)
+        }
+        count += 1000
+    }
+    return uint64(float64(count) / time.Since(start).Seconds()), nil
+}
+
+// NewPuzzle locks secret behind the given number of sequential squarings
+func NewPuzzle(secret []byte, squarings uint64) (*Puzzle, error) {
+    n, phi, err := puzzleModulus()
+    if err != nil {
+        return nil, err
+    }
+    a, err := rand.Int(rand.Reader, new(big.Int).Sub(n, big.NewInt(3)))
+    if err != nil {
+        return nil, err
+    }
+    a.Add(a, big.NewInt(2))
+
+    // Knowing phi(N) reduces the exponent 2^T to something cheap
+    e := new(big.Int).Exp(big.NewInt(2), new(big.Int).SetUint64(squarings), phi)
+    b := new(big.Int).Exp(a, e, n)
+
+    lockedKey, err := puzzleSeal(b, n, secret)
+    if err != nil {
+        return nil, err
+    }
+
+    return &Puzzle{N: n, A: a, T: squarings, LockedKey: lockedKey}, nil
+}
+
+// Solve performs the sequential squarings and returns the locked secret
+func (p *Puzzle) Solve() ([]byte, error) {
+    b := new(big.Int).Set(p.A)
+    for i := uint64(0); i < p.T; i++ {
+        b.Mul(b, b).Mod(b, p.N)
+    }
+    return puzzleOpen(b, p.N, p.LockedKey)
+}
+
+// Marshal encodes the puzzle as length-prefixed N, A and locked key around T
+func (p *Puzzle) Marshal() []byte {
+    var out []byte
+    for _, field := range [][]byte{p.N.Bytes(), p.A.Bytes(), p.LockedKey} {
+        out = binary.BigEndian.AppendUint16(out, uint16(len(field)))
+        out = append(out, field...)
+    }
+    return binary.BigEndian.AppendUint64(out, p.T)
+}
+
+// UnmarshalPuzzle decodes a puzzle produced by Marshal
+func UnmarshalPuzzle(data []byte) (*Puzzle, error) {
+    var fields [3][]byte
+    for i := range fields {
+        if len(data) < 2 {
+            return nil, ErrMalformedPuzzle
+        }
+        size := int(binary.BigEndian.Uint16(data))
+        data = data[2:]
+        if len(data) < size {
+            return nil, ErrMalformedPuzzle
+        }
+        fields[i], data = data[:size], data[size:]
+    }
+    if len(data) != 8 {
+        return nil, ErrMalformedPuzzle
+    }
+
+    return &Puzzle{
+        N:         new(big.Int).SetBytes(fields[0]),
+        A:         new(big.Int).SetBytes(fields[1]),
+        T:         binary.BigEndian.Uint64(data),
+        LockedKey: append([]byte{}, fields[2]...),
+    }, nil
+}
+
+func puzzleModulus() (*big.Int, *big.Int, error) {
+    p, err := rand.Prime(rand.Reader, puzzleModulusBits/2)
+    if err != nil {
+        return nil, nil, err
+    }
+    q, err := rand.Prime(rand.Reader, puzzleModulusBits/2)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    n := new(big.Int).Mul(p, q)
+    phi := new(big.Int).Mul(new(big.Int).Sub(p, big.NewInt(1)), new(big.Int).Sub(q, big.NewInt(1)))
+    return n, phi, nil
+}
+
+// puzzleKey hashes the solution into an AES key. Each puzzle has its own
+// modulus and base, so the key is never reused and a fixed nonce is safe.
+func puzzleKey(b, n *big.Int) []byte {
+    key := sha256.Sum256(b.FillBytes(make([]byte, (n.BitLen()+7)/8)))
+    return key[:]
+}
+
+func puzzleSeal(b, n *big.Int, secret []byte) ([]byte, error) {
+    aead, err := newAEAD(puzzleKey(b, n))
+    if err != nil {
+        return nil, err
+    }
+    return aead.Seal(nil, make([]byte, aead.NonceSize()), secret, nil), nil
+}
+
+func puzzleOpen(b, n *big.Int, lockedKey []byte) ([]byte, error) {
+    aead, err := newAEAD(puzzleKey(b, n))
+    if err != nil {
+        return nil, err
+    }
+    secret, err := aead.Open(nil, make([]byte, aead.NonceSize()), lockedKey, nil)
+    if err != nil {
+        return nil, fmt.Errorf("puzzle solution does not open the locked key: %v", err)
+    }
+    return secret, nil
+}
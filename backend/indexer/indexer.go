@@ -0,0 +1,583 @@
+package indexer
+
+import (
+    "context"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "math/big"
+    "os"
+    "sync"
+    "time"
+
+    "web3server/bindings"
+    "web3server/reorg"
+
+    "github.com/ethereum/go-ethereum"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/common/hexutil"
+    "github.com/ethereum/go-ethereum/core/types"
+    bolt "go.etcd.io/bbolt"
+)
+
+// Indexed event names
+const (
+    EventReleaseEncryptedData = "ReleaseEncryptedData"
+    EventKeyReleaseRequested  = "KeyReleaseRequested"
+    EventKeyReleased          = "KeyReleased"
+)
+
+var (
+    eventsBucket      = []byte("events")
+    unconfirmedBucket = []byte("unconfirmed")
+    metaBucket        = []byte("meta")
+    lastBlockKey      = []byte("lastBlock")
+)
+
+// Event is a decoded TwoPhaseCommit log
+type Event struct {
+    Name        string        `json:"name"`
+    BlockNumber uint64        `json:"blockNumber"`
+    BlockHash   common.Hash   `json:"blockHash"`
+    TxHash      common.Hash   `json:"transactionHash"`
+    LogIndex    uint          `json:"logIndex"`
+    Owner       string        `json:"owner"`
+    DataName    string        `json:"dataName"`
+    ReleaseTime uint64        `json:"releaseTime,omitempty"`
+    Hash        hexutil.Bytes `json:"hash,omitempty"`
+    PrivateKey  hexutil.Bytes `json:"privateKey,omitempty"`
+    Index       uint64        `json:"index,omitempty"`
+    Confirmed   bool          `json:"confirmed"`
+}
+
+// Filter narrows an event query; empty fields match everything
+type Filter struct {
+    Name          string
+    Owner         string
+    DataName      string
+    ConfirmedOnly bool
+}
+
+func (f Filter) matches(e Event) bool {
+    return (!f.ConfirmedOnly || e.Confirmed) &&
+        (f.Name == "" || f.Name == e.Name) &&
+        (f.Owner == "" || f.Owner == e.Owner) &&
+        (f.DataName == "" || f.DataName == e.DataName)
+}
+
+// Backend is the part of a client needed to read and follow logs
+type Backend interface {
+    ethereum.LogFilterer
+    BlockNumber(ctx context.Context) (uint64, error)
+    HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// IndexerConfig holds the settings for indexing contract events
+type IndexerConfig struct {
+    Client          Backend
+    Contract        *bindings.TwoPhaseCommit
+    ContractAddress common.Address
+    Path            string
+    StartBlock      uint64
+    ChunkSize       uint64
+    RetryDelay      time.Duration
+    // Confirmations is how many blocks, counting its own, an event needs
+    // before it is treated as final
+    Confirmations   uint64
+    ConfirmInterval time.Duration
+    // OnConfirmed is called once an event has enough confirmations
+    OnConfirmed func(Event)
+    // OnRemoved is called when a reorg drops an event that was indexed
+    OnRemoved func(Event)
+}
+
+// Indexer backfills contract events from a start block with chunked
+// FilterLogs, persists them, and then follows new blocks with a live
+// subscription. After a subscription drops it backfills the gap again.
+type Indexer struct {
+    Config IndexerConfig
+    db     *bolt.DB
+    mu     sync.Mutex
+    live   bool
+    head   uint64
+    reorgs *reorg.Tracker
+    logger *log.Logger
+}
+
+// Status reports how far the indexer has got
+type Status struct {
+    StartBlock    uint64 `json:"startBlock"`
+    LastBlock     uint64 `json:"lastBlock"`
+    Head          uint64 `json:"head"`
+    Live          bool   `json:"live"`
+    Events        int    `json:"events"`
+    Unconfirmed   int    `json:"unconfirmed"`
+    Confirmations uint64 `json:"confirmations"`
+}
+
+// NewIndexer opens (or creates) the event database at config.Path
+func NewIndexer(config IndexerConfig) (*Indexer, error) {
+    if config.Client == nil || config.Contract == nil {
+        return nil, errors.New("indexer requires a client and contract")
+    }
+    if config.Path == "" {
+        config.Path = "events.db"
+    }
+    if config.ChunkSize == 0 {
+        config.ChunkSize = 2000
+    }
+    if config.RetryDelay == 0 {
+        config.RetryDelay = 5 * time.Second
+    }
+    if config.Confirmations == 0 {
+        config.Confirmations = 1
+    }
+    if config.ConfirmInterval == 0 {
+        config.ConfirmInterval = 5 * time.Second
+    }
+
+    db, err := bolt.Open(config.Path, 0600, &bolt.Options{Timeout: time.Second})
+    if err != nil {
+        return nil, fmt.Errorf("failed to open event index %s: %w", config.Path, err)
+    }
+    err = db.Update(func(tx *bolt.Tx) error {
+        for _, bucket := range [][]byte{eventsBucket, unconfirmedBucket, metaBucket} {
+            if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to initialize event index: %w", err)
+    }
+
+    return &Indexer{
+        Config: config,
+        db:     db,
+        reorgs: reorg.NewTracker(),
+        logger: log.New(os.Stdout, "[Indexer] ", log.LstdFlags|log.Lmicroseconds),
+    }, nil
+}
+
+// Start runs the backfill and live subscription in the background
+func (ix *Indexer) Start(ctx context.Context) {
+    go func() {
+        for {
+            err := ix.run(ctx)
+            if ctx.Err() != nil {
+                return
+            }
+            ix.setLive(false)
+            ix.logger.Printf("Indexing interrupted, retrying in %s: %v", ix.Config.RetryDelay, err)
+
+            select {
+            case <-time.After(ix.Config.RetryDelay):
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+}
+
+// run subscribes first so nothing emitted during the backfill is missed,
+// then catches up to the head and applies live logs until the
+// subscription fails.
+func (ix *Indexer) run(ctx context.Context) error {
+    query := ix.query()
+    logs := make(chan types.Log, 256)
+    sub, err := ix.Config.Client.SubscribeFilterLogs(ctx, query, logs)
+    if err != nil {
+        return fmt.Errorf("failed to subscribe to logs: %w", err)
+    }
+    defer sub.Unsubscribe()
+
+    if err := ix.backfill(ctx); err != nil {
+        return err
+    }
+    if err := ix.confirm(ctx); err != nil {
+        return err
+    }
+    ix.setLive(true)
+    ix.logger.Printf("Caught up at block %d, following new blocks", ix.LastBlock())
+
+    ticker := time.NewTicker(ix.Config.ConfirmInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case vLog := <-logs:
+            if err := ix.apply(vLog); err != nil {
+                return err
+            }
+        case <-ticker.C:
+            if err := ix.confirm(ctx); err != nil {
+                return err
+            }
+        case err := <-sub.Err():
+            return fmt.Errorf("subscription failed: %w", err)
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+}
+
+// backfill indexes everything between the last indexed block and the head
+func (ix *Indexer) backfill(ctx context.Context) error {
+    head, err := ix.Config.Client.BlockNumber(ctx)
+    if err != nil {
+        return fmt.Errorf("failed to retrieve head block: %w", err)
+    }
+    ix.observeHead(head)
+
+    from := ix.Config.StartBlock
+    if last := ix.LastBlock(); last >= from && last > 0 {
+        from = last + 1
+    }
+
+    for from <= head {
+        to := from + ix.Config.ChunkSize - 1
+        if to > head {
+            to = head
+        }
+
+        query := ix.query()
+        query.FromBlock = new(big.Int).SetUint64(from)
+        query.ToBlock = new(big.Int).SetUint64(to)
+        logs, err := ix.Config.Client.FilterLogs(ctx, query)
+        if err != nil {
+            return fmt.Errorf("failed to filter logs %d-%d: %w", from, to, err)
+        }
+
+        for _, vLog := range logs {
+            if err := ix.apply(vLog); err != nil {
+                return err
+            }
+        }
+        if err := ix.setLastBlock(to); err != nil {
+            return err
+        }
+        if len(logs) > 0 {
+            ix.logger.Printf("Backfilled %d events from blocks %d-%d", len(logs), from, to)
+        }
+        from = to + 1
+    }
+    return nil
+}
+
+// apply stores or removes a single log. Events are keyed by block and log
+// index, so logs seen by both the backfill and the subscription are
+// stored once. New events start out unconfirmed.
+func (ix *Indexer) apply(vLog types.Log) error {
+    if len(vLog.Topics) == 0 {
+        return nil
+    }
+    ix.observeHead(vLog.BlockNumber)
+    key := eventKey(vLog.BlockNumber, vLog.Index)
+
+    if vLog.Removed {
+        return ix.remove(key, vLog.BlockHash)
+    }
+
+    event, err := ix.decode(vLog)
+    if err != nil {
+        ix.logger.Printf("Failed to decode log %s/%d: %v", vLog.TxHash.Hex(), vLog.Index, err)
+        return nil
+    }
+
+    existing, found, err := ix.get(key)
+    if err != nil {
+        return err
+    }
+    if found && existing.BlockHash == event.BlockHash {
+        return nil
+    }
+    if found {
+        // Another block now sits at this position, so the old one was reorged out
+        if err := ix.remove(key, existing.BlockHash); err != nil {
+            return err
+        }
+    }
+
+    value, err := json.Marshal(event)
+    if err != nil {
+        return err
+    }
+    return ix.db.Update(func(tx *bolt.Tx) error {
+        if err := tx.Bucket(eventsBucket).Put(key, value); err != nil {
+            return err
+        }
+        if err := tx.Bucket(unconfirmedBucket).Put(key, nil); err != nil {
+            return err
+        }
+        // A live log for block N may be followed by others in the same block,
+        // so only mark the blocks before it as complete.
+        if vLog.BlockNumber > 0 && vLog.BlockNumber-1 > readLastBlock(tx) {
+            return tx.Bucket(metaBucket).Put(lastBlockKey, binary.BigEndian.AppendUint64(nil, vLog.BlockNumber-1))
+        }
+        return nil
+    })
+}
+
+// remove drops the event at key if it still belongs to blockHash, records
+// the reorg and lets the caller roll back anything derived from it.
+func (ix *Indexer) remove(key []byte, blockHash common.Hash) error {
+    event, found, err := ix.get(key)
+    if err != nil || !found || event.BlockHash != blockHash {
+        return err
+    }
+
+    err = ix.db.Update(func(tx *bolt.Tx) error {
+        if err := tx.Bucket(unconfirmedBucket).Delete(key); err != nil {
+            return err
+        }
+        return tx.Bucket(eventsBucket).Delete(key)
+    })
+    if err != nil {
+        return err
+    }
+
+    ix.mu.Lock()
+    head := ix.head
+    ix.mu.Unlock()
+    depth := reorg.Depth(head, event.BlockNumber)
+
+    ix.logger.Printf("Reorg removed %s for %s/%s at block %d (depth %d, confirmed %t)",
+        event.Name, event.Owner, event.DataName, event.BlockNumber, depth, event.Confirmed)
+    ix.reorgs.Record(reorg.Reorg{
+        BlockNumber: event.BlockNumber,
+        Depth:       depth,
+        TxHash:      event.TxHash.Hex(),
+        Event:       event.Name,
+    })
+    if ix.Config.OnRemoved != nil {
+        ix.Config.OnRemoved(event)
+    }
+    return nil
+}
+
+// confirm finalizes unconfirmed events that are deep enough, after checking
+// their block is still canonical.
+func (ix *Indexer) confirm(ctx context.Context) error {
+    head, err := ix.Config.Client.BlockNumber(ctx)
+    if err != nil {
+        return fmt.Errorf("failed to retrieve head block: %w", err)
+    }
+    ix.observeHead(head)
+
+    var keys [][]byte
+    err = ix.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(unconfirmedBucket).ForEach(func(key, _ []byte) error {
+            keys = append(keys, append([]byte{}, key...))
+            return nil
+        })
+    })
+    if err != nil {
+        return err
+    }
+
+    for _, key := range keys {
+        event, found, err := ix.get(key)
+        if err != nil {
+            return err
+        }
+        if !found || reorg.Depth(head, event.BlockNumber) < ix.Config.Confirmations {
+            continue
+        }
+
+        header, err := ix.Config.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(event.BlockNumber))
+        if err != nil {
+            return fmt.Errorf("failed to fetch header %d: %w", event.BlockNumber, err)
+        }
+        if header.Hash() != event.BlockHash {
+            if err := ix.remove(key, event.BlockHash); err != nil {
+                return err
+            }
+            continue
+        }
+
+        event.Confirmed = true
+        value, err := json.Marshal(event)
+        if err != nil {
+            return err
+        }
+        err = ix.db.Update(func(tx *bolt.Tx) error {
+            if err := tx.Bucket(eventsBucket).Put(key, value); err != nil {
+                return err
+            }
+            return tx.Bucket(unconfirmedBucket).Delete(key)
+        })
+        if err != nil {
+            return err
+        }
+
+        if ix.Config.OnConfirmed != nil {
+            ix.Config.OnConfirmed(event)
+        }
+    }
+    return nil
+}
+
+func (ix *Indexer) get(key []byte) (Event, bool, error) {
+    var event Event
+    found := false
+    err := ix.db.View(func(tx *bolt.Tx) error {
+        value := tx.Bucket(eventsBucket).Get(key)
+        if value == nil {
+            return nil
+        }
+        found = true
+        return json.Unmarshal(value, &event)
+    })
+    return event, found, err
+}
+
+func (ix *Indexer) observeHead(block uint64) {
+    ix.mu.Lock()
+    if block > ix.head {
+        ix.head = block
+    }
+    ix.mu.Unlock()
+}
+
+// decode turns a raw log into an Event through the generated parsers
+func (ix *Indexer) decode(vLog types.Log) (Event, error) {
+    event := Event{
+        BlockNumber: vLog.BlockNumber,
+        BlockHash:   vLog.BlockHash,
+        TxHash:      vLog.TxHash,
+        LogIndex:    vLog.Index,
+    }
+
+    switch vLog.Topics[0] {
+    case bindings.EventID(EventReleaseEncryptedData):
+        parsed, err := ix.Config.Contract.ParseReleaseEncryptedData(vLog)
+        if err != nil {
+            return Event{}, err
+        }
+        event.Name = EventReleaseEncryptedData
+        event.Owner, event.DataName = parsed.Owner, parsed.DataName
+        event.ReleaseTime = parsed.ReleaseTime.Uint64()
+        event.Hash = parsed.Hash
+
+    case bindings.EventID(EventKeyReleaseRequested):
+        parsed, err := ix.Config.Contract.ParseKeyReleaseRequested(vLog)
+        if err != nil {
+            return Event{}, err
+        }
+        event.Name = EventKeyReleaseRequested
+        event.Owner, event.DataName = parsed.Owner, parsed.DataName
+        event.Index = parsed.Index.Uint64()
+
+    case bindings.EventID(EventKeyReleased):
+        parsed, err := ix.Config.Contract.ParseKeyReleased(vLog)
+        if err != nil {
+            return Event{}, err
+        }
+        event.Name = EventKeyReleased
+        event.Owner, event.DataName = parsed.Owner, parsed.DataName
+        event.PrivateKey = parsed.PrivateKey
+
+    default:
+        return Event{}, fmt.Errorf("unknown event topic %s", vLog.Topics[0].Hex())
+    }
+    return event, nil
+}
+
+// Events returns the indexed events matching filter in chain order
+func (ix *Indexer) Events(filter Filter) ([]Event, error) {
+    var events []Event
+    err := ix.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(eventsBucket).ForEach(func(_, value []byte) error {
+            var event Event
+            if err := json.Unmarshal(value, &event); err != nil {
+                return err
+            }
+            if filter.matches(event) {
+                events = append(events, event)
+            }
+            return nil
+        })
+    })
+    return events, err
+}
+
+// LastBlock returns the highest block whose events are all indexed
+func (ix *Indexer) LastBlock() uint64 {
+    var last uint64
+    ix.db.View(func(tx *bolt.Tx) error {
+        last = readLastBlock(tx)
+        return nil
+    })
+    return last
+}
+
+// Status summarizes the indexer's progress
+func (ix *Indexer) Status() Status {
+    status := Status{StartBlock: ix.Config.StartBlock, Confirmations: ix.Config.Confirmations}
+    ix.db.View(func(tx *bolt.Tx) error {
+        status.LastBlock = readLastBlock(tx)
+        status.Events = tx.Bucket(eventsBucket).Stats().KeyN
+        status.Unconfirmed = tx.Bucket(unconfirmedBucket).Stats().KeyN
+        return nil
+    })
+
+    ix.mu.Lock()
+    status.Live = ix.live
+    status.Head = ix.head
+    ix.mu.Unlock()
+    return status
+}
+
+// Reorgs returns the reorgs the indexer has observed
+func (ix *Indexer) Reorgs() reorg.Stats {
+    return ix.reorgs.Stats()
+}
+
+// Close releases the event database
+func (ix *Indexer) Close() error {
+    return ix.db.Close()
+}
+
+func (ix *Indexer) query() ethereum.FilterQuery {
+    return ethereum.FilterQuery{
+        Addresses: []common.Address{ix.Config.ContractAddress},
+        Topics: [][]common.Hash{{
+            bindings.EventID(EventReleaseEncryptedData),
+            bindings.EventID(EventKeyReleaseRequested),
+            bindings.EventID(EventKeyReleased),
+        }},
+    }
+}
+
+func (ix *Indexer) setLive(live bool) {
+    ix.mu.Lock()
+    ix.live = live
+    ix.mu.Unlock()
+}
+
+func (ix *Indexer) setLastBlock(block uint64) error {
+    return ix.db.Update(func(tx *bolt.Tx) error {
+        if block <= readLastBlock(tx) {
+            return nil
+        }
+        return tx.Bucket(metaBucket).Put(lastBlockKey, binary.BigEndian.AppendUint64(nil, block))
+    })
+}
+
+func readLastBlock(tx *bolt.Tx) uint64 {
+    value := tx.Bucket(metaBucket).Get(lastBlockKey)
+    if len(value) != 8 {
+        return 0
+    }
+    return binary.BigEndian.Uint64(value)
+}
+
+// eventKey sorts events by block number and then log index
+func eventKey(block uint64, index uint) []byte {
+    key := binary.BigEndian.AppendUint64(nil, block)
+    return binary.BigEndian.AppendUint32(key, uint32(index))
+}
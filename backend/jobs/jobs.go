@@ -0,0 +1,413 @@
+package jobs
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "math/big"
+    "os"
+    "sync"
+    "time"
+
+    "github.com/ethereum/go-ethereum"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/core/types"
+    bolt "go.etcd.io/bbolt"
+)
+
+// Status describes where an upload transaction is on its way to the chain
+type Status string
+
+const (
+    StatusPending   Status = "pending"
+    StatusMined     Status = "mined"
+    StatusConfirmed Status = "confirmed"
+    StatusReverted  Status = "reverted"
+    StatusDropped   Status = "dropped"
+    StatusCancelled Status = "cancelled"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+var (
+    jobsBucket = []byte("jobs")
+    // activeBucket indexes the IDs of jobs that are not final yet
+    activeBucket = []byte("active")
+)
+
+// Job is a submitted upload transaction and what is known about its receipt
+type Job struct {
+    ID                string    `json:"id"`
+    TxHash            string    `json:"transactionHash"`
+    From              string    `json:"from"`
+    Nonce             uint64    `json:"nonce"`
+    Owner             string    `json:"owner"`
+    DataName          string    `json:"dataName"`
+    ReleaseTime       uint64    `json:"releaseTime"`
+    KeyEscrowed       bool      `json:"keyEscrowed"`
+    Status            Status    `json:"status"`
+    BlockNumber       uint64    `json:"blockNumber,omitempty"`
+    GasUsed           uint64    `json:"gasUsed,omitempty"`
+    EffectiveGasPrice string    `json:"effectiveGasPrice,omitempty"`
+    Confirmations     uint64    `json:"confirmations"`
+    Error             string    `json:"error,omitempty"`
+    CreatedAt         time.Time `json:"createdAt"`
+    UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// Final reports whether the job will not change any more
+func (j Job) Final() bool {
+    return j.Status == StatusConfirmed || j.Status == StatusReverted || j.Status == StatusDropped ||
+        j.Status == StatusCancelled
+}
+
+// Backend is the part of a client needed to follow transactions
+type Backend interface {
+    BlockNumber(ctx context.Context) (uint64, error)
+    NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+    TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+    TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error)
+}
+
+// TrackerConfig holds the settings for following upload transactions
+type TrackerConfig struct {
+    Client        Backend
+    Path          string
+    Confirmations uint64
+    PollInterval  time.Duration
+    DropTimeout   time.Duration
+    // OnConfirmed runs before a job is stored as confirmed; an error keeps
+    // the job active so it is called again on the next poll
+    OnConfirmed func(Job) error
+}
+
+// Tracker follows submitted transactions until they are confirmed, reverted
+// or dropped, persisting every job so polling survives restarts.
+type Tracker struct {
+    Config TrackerConfig
+    db     *bolt.DB
+    mu     sync.Mutex
+    logger *log.Logger
+}
+
+// NewTracker opens (or creates) the job database at config.Path
+func NewTracker(config TrackerConfig) (*Tracker, error) {
+    if config.Client == nil {
+        return nil, errors.New("tracker requires a client")
+    }
+    if config.Path == "" {
+        config.Path = "jobs.db"
+    }
+    if config.Confirmations == 0 {
+        config.Confirmations = 1
+    }
+    if config.PollInterval == 0 {
+        config.PollInterval = 5 * time.Second
+    }
+    if config.DropTimeout == 0 {
+        config.DropTimeout = 30 * time.Minute
+    }
+
+    db, err := bolt.Open(config.Path, 0600, &bolt.Options{Timeout: time.Second})
+    if err != nil {
+        return nil, fmt.Errorf("failed to open job store %s: %w", config.Path, err)
+    }
+    err = db.Update(func(tx *bolt.Tx) error {
+        jobs, err := tx.CreateBucketIfNotExists(jobsBucket)
+        if err != nil {
+            return err
+        }
+        if tx.Bucket(activeBucket) != nil {
+            return nil
+        }
+        // Stores written before the index existed are indexed once
+        active, err := tx.CreateBucket(activeBucket)
+        if err != nil {
+            return err
+        }
+        return jobs.ForEach(func(id, value []byte) error {
+            var job Job
+            if err := json.Unmarshal(value, &job); err != nil {
+                return err
+            }
+            if job.Final() {
+                return nil
+            }
+            return active.Put(id, []byte{})
+        })
+    })
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to initialize job store: %w", err)
+    }
+
+    return &Tracker{
+        Config: config,
+        db:     db,
+        logger: log.New(os.Stdout, "[Jobs] ", log.LstdFlags|log.Lmicroseconds),
+    }, nil
+}
+
+// Submit records a freshly sent transaction as a pending job
+func (t *Tracker) Submit(tx *types.Transaction, from common.Address, owner, dataName string, releaseTime uint64, keyEscrowed bool) (Job, error) {
+    id := make([]byte, 16)
+    if _, err := rand.Read(id); err != nil {
+        return Job{}, err
+    }
+
+    now := time.Now()
+    job := Job{
+        ID:          hex.EncodeToString(id),
+        TxHash:      tx.Hash().Hex(),
+        From:        from.Hex(),
+        Nonce:       tx.Nonce(),
+        Owner:       owner,
+        DataName:    dataName,
+        ReleaseTime: releaseTime,
+        KeyEscrowed: keyEscrowed,
+        Status:      StatusPending,
+        CreatedAt:   now,
+        UpdatedAt:   now,
+    }
+    if err := t.put(job); err != nil {
+        return Job{}, err
+    }
+    return job, nil
+}
+
+// Get returns the job with the given ID
+func (t *Tracker) Get(id string) (Job, error) {
+    var job Job
+    err := t.db.View(func(tx *bolt.Tx) error {
+        value := tx.Bucket(jobsBucket).Get([]byte(id))
+        if value == nil {
+            return ErrJobNotFound
+        }
+        return json.Unmarshal(value, &job)
+    })
+    return job, err
+}
+
+// Active returns every job that is still waiting for a final status
+func (t *Tracker) Active() ([]Job, error) {
+    var active []Job
+    err := t.db.View(func(tx *bolt.Tx) error {
+        jobs := tx.Bucket(jobsBucket)
+        return tx.Bucket(activeBucket).ForEach(func(id, _ []byte) error {
+            var job Job
+            if err := json.Unmarshal(jobs.Get(id), &job); err != nil {
+                return err
+            }
+            active = append(active, job)
+            return nil
+        })
+    })
+    return active, err
+}
+
+// Update applies fn to a stored job and persists the result
+func (t *Tracker) Update(id string, fn func(*Job)) (Job, error) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    job, err := t.Get(id)
+    if err != nil {
+        return Job{}, err
+    }
+    fn(&job)
+    job.UpdatedAt = time.Now()
+    return job, t.put(job)
+}
+
+// Replace points the job following oldHash at a replacement transaction.
+// A cancelled upload can never write its record, so its job ends there.
+func (t *Tracker) Replace(oldHash, newHash common.Hash, cancelled bool) error {
+    active, err := t.Active()
+    if err != nil {
+        return err
+    }
+    for _, job := range active {
+        if job.TxHash == oldHash.Hex() {
+            _, err := t.Update(job.ID, func(j *Job) {
+                j.TxHash = newHash.Hex()
+                if cancelled {
+                    j.Status = StatusCancelled
+                    j.Error = fmt.Sprintf("upload was cancelled by %s", newHash.Hex())
+                }
+            })
+            if err == nil && cancelled {
+                t.logger.Printf("Job %s cancelled (%s)", job.ID, newHash.Hex())
+            }
+            return err
+        }
+    }
+    return nil
+}
+
+// Start polls active jobs in the background until ctx is cancelled. Jobs
+// left pending by a previous run are picked up again.
+func (t *Tracker) Start(ctx context.Context) {
+    go func() {
+        ticker := time.NewTicker(t.Config.PollInterval)
+        defer ticker.Stop()
+
+        for {
+            t.poll(ctx)
+
+            select {
+            case <-ticker.C:
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+}
+
+// Close releases the job database
+func (t *Tracker) Close() error {
+    return t.db.Close()
+}
+
+func (t *Tracker) poll(ctx context.Context) {
+    active, err := t.Active()
+    if err != nil {
+        t.logger.Printf("Failed to load active jobs: %v", err)
+        return
+    }
+    if len(active) == 0 {
+        return
+    }
+
+    head, err := t.Config.Client.BlockNumber(ctx)
+    if err != nil {
+        t.logger.Printf("Failed to retrieve head block: %v", err)
+        return
+    }
+
+    for _, job := range active {
+        if err := t.check(ctx, job, head); err != nil {
+            t.logger.Printf("Failed to check job %s: %v", job.ID, err)
+        }
+    }
+}
+
+// check moves a single job forward based on its receipt
+func (t *Tracker) check(ctx context.Context, job Job, head uint64) error {
+    receipt, err := t.Config.Client.TransactionReceipt(ctx, common.HexToHash(job.TxHash))
+    if errors.Is(err, ethereum.NotFound) {
+        return t.checkUnmined(ctx, job)
+    }
+    if err != nil {
+        return err
+    }
+
+    block := receipt.BlockNumber.Uint64()
+    confirmations := uint64(0)
+    if head >= block {
+        confirmations = head - block + 1
+    }
+
+    status := StatusMined
+    switch {
+    case receipt.Status != types.ReceiptStatusSuccessful:
+        status = StatusReverted
+    case confirmations >= t.Config.Confirmations:
+        status = StatusConfirmed
+    }
+    apply := func(j *Job) {
+        j.BlockNumber = block
+        j.GasUsed = receipt.GasUsed
+        j.EffectiveGasPrice = receipt.EffectiveGasPrice.String()
+        j.Confirmations = confirmations
+        j.Status = status
+        if status == StatusReverted {
+            j.Error = fmt.Sprintf("transaction reverted in block %d", block)
+        }
+    }
+
+    // The callback runs before the job is final, so a failure or a crash in
+    // between leaves the job active and the next poll calls it again
+    var callbackErr error
+    if status == StatusConfirmed && t.Config.OnConfirmed != nil {
+        confirmed := job
+        apply(&confirmed)
+        if callbackErr = t.Config.OnConfirmed(confirmed); callbackErr != nil {
+            status = StatusMined
+        }
+    }
+
+    updated, err := t.Update(job.ID, apply)
+    if err != nil {
+        return err
+    }
+    if updated.Status != job.Status {
+        t.logger.Printf("Job %s is %s (%s)", job.ID, updated.Status, job.TxHash)
+    }
+    if callbackErr != nil {
+        return fmt.Errorf("confirmation callback failed: %w", callbackErr)
+    }
+    return nil
+}
+
+// checkUnmined handles a job whose transaction has no receipt. A mined job
+// without a receipt was reorged out; a nonce that has moved on without this
+// transaction, or a transaction the node forgot about, means it was dropped.
+func (t *Tracker) checkUnmined(ctx context.Context, job Job) error {
+    if job.Status == StatusMined {
+        t.logger.Printf("Job %s lost its receipt, back to pending", job.ID)
+        _, err := t.Update(job.ID, func(j *Job) {
+            j.Status = StatusPending
+            j.BlockNumber, j.GasUsed, j.EffectiveGasPrice, j.Confirmations = 0, 0, "", 0
+        })
+        return err
+    }
+
+    nonce, err := t.Config.Client.NonceAt(ctx, common.HexToAddress(job.From), nil)
+    if err != nil {
+        return err
+    }
+
+    reason := ""
+    if nonce > job.Nonce {
+        // The transaction may have been mined since the receipt lookup
+        if _, err := t.Config.Client.TransactionReceipt(ctx, common.HexToHash(job.TxHash)); err == nil {
+            return nil
+        }
+        reason = fmt.Sprintf("nonce %d was used by another transaction", job.Nonce)
+    } else if time.Since(job.CreatedAt) > t.Config.DropTimeout {
+        _, _, err := t.Config.Client.TransactionByHash(ctx, common.HexToHash(job.TxHash))
+        if errors.Is(err, ethereum.NotFound) {
+            reason = fmt.Sprintf("transaction not seen by the node after %s", t.Config.DropTimeout)
+        }
+    }
+    if reason == "" {
+        return nil
+    }
+
+    t.logger.Printf("Job %s dropped: %s", job.ID, reason)
+    _, err = t.Update(job.ID, func(j *Job) {
+        j.Status = StatusDropped
+        j.Error = reason
+    })
+    return err
+}
+
+func (t *Tracker) put(job Job) error {
+    value, err := json.Marshal(job)
+    if err != nil {
+        return err
+    }
+    return t.db.Update(func(tx *bolt.Tx) error {
+        if err := tx.Bucket(jobsBucket).Put([]byte(job.ID), value); err != nil {
+            return err
+        }
+        if job.Final() {
+            return tx.Bucket(activeBucket).Delete([]byte(job.ID))
+        }
+        return tx.Bucket(activeBucket).Put([]byte(job.ID), []byte{})
+    })
+}
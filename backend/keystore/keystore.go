@@ -0,0 +1,142 @@
+package keystore
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "encoding/binary"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+)
+
+var (
+    ErrKeyNotFound = errors.New("release key not found")
+    ErrKeyExists   = errors.New("release key already stored")
+)
+
+// KeyStore holds release keys indexed by (owner, dataName). Put never
+// replaces a stored key; Delete it first.
+type KeyStore interface {
+    Put(owner, dataName string, key []byte) error
+    Get(owner, dataName string) ([]byte, error)
+    Delete(owner, dataName string) error
+    Close() error
+}
+
+var keysBucket = []byte("releaseKeys")
+
+// BoltKeyStore persists release keys in an embedded bbolt database, each one
+// sealed with AES-256-GCM under a master key.
+type BoltKeyStore struct {
+    db   *bolt.DB
+    aead cipher.AEAD
+}
+
+// ParseMasterKey decodes a hex encoded 32 byte master key
+func ParseMasterKey(encoded string) ([]byte, error) {
+    masterKey, err := hex.DecodeString(encoded)
+    if err != nil {
+        return nil, fmt.Errorf("master key must be hex encoded: %w", err)
+    }
+    if len(masterKey) != 32 {
+        return nil, fmt.Errorf("master key must be 32 bytes, got %d", len(masterKey))
+    }
+    return masterKey, nil
+}
+
+// NewBoltKeyStore opens (or creates) the keystore at path
+func NewBoltKeyStore(path string, masterKey []byte) (*BoltKeyStore, error) {
+    block, err := aes.NewCipher(masterKey)
+    if err != nil {
+        return nil, fmt.Errorf("invalid master key: %w", err)
+    }
+    aead, err := cipher.NewGCM(block)
+    if err != nil {
+        return nil, err
+    }
+
+    db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+    if err != nil {
+        return nil, fmt.Errorf("failed to open keystore %s: %w", path, err)
+    }
+
+    err = db.Update(func(tx *bolt.Tx) error {
+        _, err := tx.CreateBucketIfNotExists(keysBucket)
+        return err
+    })
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to initialize keystore: %w", err)
+    }
+
+    return &BoltKeyStore{db: db, aead: aead}, nil
+}
+
+// RecordKey length-prefixes both parts so no (owner, dataName) pair can
+// collide with another one. Other stores keyed by record use it too.
+func RecordKey(owner, dataName string) []byte {
+    key := binary.BigEndian.AppendUint32(nil, uint32(len(owner)))
+    key = append(key, owner...)
+    return append(key, dataName...)
+}
+
+func (ks *BoltKeyStore) Put(owner, dataName string, key []byte) error {
+    id := RecordKey(owner, dataName)
+
+    nonce := make([]byte, ks.aead.NonceSize())
+    if _, err := rand.Read(nonce); err != nil {
+        return err
+    }
+    // Bind the sealed key to its record so entries cannot be swapped on disk
+    sealed := ks.aead.Seal(nonce, nonce, key, id)
+
+    return ks.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(keysBucket)
+        if bucket.Get(id) != nil {
+            return fmt.Errorf("%w for %s/%s", ErrKeyExists, owner, dataName)
+        }
+        return bucket.Put(id, sealed)
+    })
+}
+
+func (ks *BoltKeyStore) Get(owner, dataName string) ([]byte, error) {
+    id := RecordKey(owner, dataName)
+
+    var sealed []byte
+    err := ks.db.View(func(tx *bolt.Tx) error {
+        value := tx.Bucket(keysBucket).Get(id)
+        if value == nil {
+            return ErrKeyNotFound
+        }
+        sealed = append([]byte{}, value...)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    if len(sealed) < ks.aead.NonceSize() {
+        return nil, fmt.Errorf("corrupt keystore entry for %s/%s", owner, dataName)
+    }
+    nonce, ciphertext := sealed[:ks.aead.NonceSize()], sealed[ks.aead.NonceSize():]
+
+    key, err := ks.aead.Open(nil, nonce, ciphertext, id)
+    if err != nil {
+        return nil, fmt.Errorf("failed to unseal key for %s/%s: %w", owner, dataName, err)
+    }
+    return key, nil
+}
+
+func (ks *BoltKeyStore) Delete(owner, dataName string) error {
+    return ks.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(keysBucket).Delete(RecordKey(owner, dataName))
+    })
+}
+
+func (ks *BoltKeyStore) Close() error {
+    return ks.db.Close()
+}
@@ -2,113 +2,209 @@ package main
 
 import (
     "context"
-    "crypto/sha256"
-    "encoding/json"
+    "errors"
+    "flag"
     "fmt"
     "log"
     "math/big"
+    "net/url"
     "os"
     "strconv"
     "strings"
     "time"
 
+    "web3server/bindings"
+    "web3server/blobstore"
+    "web3server/config"
+    "web3server/custody"
     h "web3server/helper"
+    "web3server/indexer"
+    "web3server/jobs"
+    ks "web3server/keystore"
+    "web3server/records"
+    r "web3server/release"
+    "web3server/rpcpool"
+    "web3server/signer"
     t "web3server/testing"
+    "web3server/txmgr"
+    "web3server/upkeep"
+    v "web3server/verify"
 
-    "github.com/ethereum/go-ethereum"
-    "github.com/ethereum/go-ethereum/accounts/abi"
     "github.com/ethereum/go-ethereum/accounts/abi/bind"
     "github.com/ethereum/go-ethereum/common"
     "github.com/ethereum/go-ethereum/common/hexutil"
-    "github.com/ethereum/go-ethereum/core/types"
-    "github.com/ethereum/go-ethereum/crypto"
-    "github.com/ethereum/go-ethereum/ethclient"
     "github.com/gin-gonic/gin"
     "github.com/joho/godotenv"
 )
 
-type Contract struct {
-    ABI json.RawMessage `json:"abi"`
-}
-
-// Event structs to match contract events
-type ReleaseEncryptedDataEvent struct {
-    EncryptedData []byte
-    Owner         string
-    DataName      string
-    ReleaseTime   *big.Int
-    Hash          []byte
-}
-
-type KeyReleasedEvent struct {
-    PrivateKey []byte
-    Owner      string
-    DataName   string
-}
-
-type KeyReleaseRequestedEvent struct {
-    Owner    string
-    DataName string
-}
-
 var (
-    client          *ethclient.Client
+    client          *rpcpool.Pool
+    network         *config.Profile
+    chainID         *big.Int
     contractAddress common.Address
-    contractABI     abi.ABI
-    PrivateKey      string
+    contract        *bindings.TwoPhaseCommit
+    txSigner        signer.Signer
+    gasStrategy     txmgr.GasStrategy
+    gasMaxFeeCap    *big.Int
+    nonces          *txmgr.NonceManager
+    transactions    *txmgr.Tracker
     distributor     *t.DistributedTester
-    privKeys        map[string][]byte
+    releaser        *r.Scheduler
+    keeper          *upkeep.Runner
+    uploads         *jobs.Tracker
+    verifier        *v.Verifier
+    events          *indexer.Indexer
+    catalog         *records.Catalog
+    keys            ks.KeyStore
+    coordinator     *custody.Coordinator
+    blobs           *blobstore.FileStore
+    blobFetcher     *blobstore.Fetcher
+    blobBaseURL     string
     encryptedData   map[string][]byte
 )
 
-func LoadABI() (abi.ABI, error) {
-    filePath := "TwoPhaseCommit.json"
-    abiBytes, err := os.ReadFile(filePath)
-    if err != nil {
-        return abi.ABI{}, fmt.Errorf("failed to read ABI file: %v", err)
+func main() {
+    if len(os.Args) > 1 && os.Args[1] == "solve" {
+        runSolve(os.Args[2:])
+        return
+    }
+    if len(os.Args) > 1 && os.Args[1] == "signer-server" {
+        runSignerServer(os.Args[2:])
+        return
+    }
+    if len(os.Args) > 1 && os.Args[1] == "simulate" {
+        runSimulate(os.Args[2:])
+        return
+    }
+    if len(os.Args) > 1 && os.Args[1] == "deploy" {
+        runDeploy(os.Args[2:])
+        return
     }
 
-    var contract Contract
-    err = json.Unmarshal(abiBytes, &contract)
-    if err != nil {
-        return abi.ABI{}, fmt.Errorf("failed to unmarshal contract JSON: %v", err)
+    if err := godotenv.Load(); err != nil {
+        log.Fatalf("Error loading .env file")
     }
 
-    parsedABI, err := abi.JSON(strings.NewReader(string(contract.ABI)))
+    configPath := flag.String("config", GetEnvDefault("NETWORK_CONFIG", "networks.yaml"), "network profiles file")
+    networkName := flag.String("network", os.Getenv("NETWORK"), "network profile to use")
+    flag.Parse()
+
+    networks, err := config.Load(*configPath)
     if err != nil {
-        return abi.ABI{}, fmt.Errorf("failed to parse ABI JSON: %v", err)
+        log.Fatalf("Failed to load network config: %v", err)
     }
+    network, err = networks.Profile(*networkName)
+    if err != nil {
+        log.Fatalf("Failed to select network: %v", err)
+    }
+    if network.ContractAddress == "" {
+        log.Fatalf("Network %s has no contract address configured", network.Name)
+    }
+    chainID = network.ChainIDBig()
+    log.Printf("Using network %s (chain %d)", network.Name, network.ChainID)
 
-    return parsedABI, nil
-}
+    txSigner, err = newSignerFromEnv(context.Background())
+    if err != nil {
+        log.Fatalf("Failed to set up transaction signer: %v", err)
+    }
+    log.Printf("Sending transactions from %s", txSigner.Address().Hex())
 
-func main() {
-    if err := godotenv.Load(); err != nil {
-        log.Fatalf("Error loading .env file")
+    if maxFee := os.Getenv("GAS_MAX_FEE_GWEI"); maxFee != "" {
+        gasMaxFeeCap, err = txmgr.ParseGwei(maxFee)
+        if err != nil {
+            log.Fatalf("Failed to parse GAS_MAX_FEE_GWEI: %v", err)
+        }
     }
-    PrivateKey = "0x" + MustGetEnv("PRIVATE_KEY")
+    gasStrategy, err = txmgr.StrategyByName(GetEnvDefault("GAS_STRATEGY", "normal"), gasMaxFeeCap)
+    if err != nil {
+        log.Fatalf("Failed to select gas strategy: %v", err)
+    }
+    log.Printf("Pricing transactions with the %s gas strategy", gasStrategy.Name())
 
-    var err error
-    client, err = ethclient.Dial("wss://api.avax-test.network/ext/bc/C/ws")
+    // Reads go to the healthiest endpoint and writes fail over between them
+    client, err = rpcpool.NewPool(context.Background(), rpcpool.PoolConfig{Endpoints: network.Endpoints})
     if err != nil {
         log.Fatalf("Failed to connect to the Ethereum client: %v", err)
     }
     defer client.Close()
 
-    contractAddress = common.HexToAddress("0xEA0243082093B09858b37f08d30531a29cA6589b")
-    contractABI, err = LoadABI()
+    nonces, err = txmgr.NewNonceManager(context.Background(), client, txSigner.Address())
+    if err != nil {
+        log.Fatalf("Failed to set up nonce manager: %v", err)
+    }
+
+    stuckTimeout, err := time.ParseDuration(GetEnvDefault("TX_STUCK_TIMEOUT", "3m"))
     if err != nil {
-        log.Fatalf("Failed to parse contract ABI: %v", err)
+        log.Fatalf("Failed to parse TX_STUCK_TIMEOUT: %v", err)
+    }
+    bumpPercent, err := strconv.ParseInt(GetEnvDefault("TX_FEE_BUMP_PERCENT", "20"), 10, 64)
+    if err != nil {
+        log.Fatalf("Failed to parse TX_FEE_BUMP_PERCENT: %v", err)
+    }
+    transactions, err = txmgr.NewTracker(txmgr.TrackerConfig{
+        Client:       client,
+        Signer:       txSigner,
+        ChainID:      chainID,
+        StuckTimeout: stuckTimeout,
+        BumpPercent:  bumpPercent,
+        MaxFeeCap:    gasMaxFeeCap,
+        OnReplaced:   onTransactionReplaced,
+    })
+    if err != nil {
+        log.Fatalf("Failed to set up transaction tracker: %v", err)
+    }
+
+    contractAddress = network.Contract()
+    contract, err = bindings.NewTwoPhaseCommit(contractAddress, client)
+    if err != nil {
+        log.Fatalf("Failed to bind contract: %v", err)
+    }
+
+    masterKey, err := ks.ParseMasterKey(MustGetEnv("KEYSTORE_MASTER_KEY"))
+    if err != nil {
+        log.Fatalf("Failed to parse keystore master key: %v", err)
+    }
+    keys, err = ks.NewBoltKeyStore(GetEnvDefault("KEYSTORE_PATH", "keystore.db"), masterKey)
+    if err != nil {
+        log.Fatalf("Failed to open keystore: %v", err)
+    }
+    defer keys.Close()
+
+    var closeCustody func()
+    coordinator, closeCustody, err = setupCustody(masterKey)
+    if err != nil {
+        log.Fatalf("Failed to set up key custody: %v", err)
+    }
+    defer closeCustody()
+
+    // Large ciphertexts can live off-chain, addressed by their sha256
+    blobs, err = blobstore.NewFileStore(GetEnvDefault("BLOB_DIR", "blobs"))
+    if err != nil {
+        log.Fatalf("Failed to open blob store: %v", err)
+    }
+    blobBaseURL = GetEnvDefault("BLOB_PUBLIC_URL", "http://localhost:8080")
+    blobFetcher, err = newBlobFetcher(blobs, blobBaseURL, os.Getenv("BLOB_ALLOWED_HOSTS"))
+    if err != nil {
+        log.Fatalf("Failed to set up blob fetcher: %v", err)
+    }
+
+    // Size time-lock puzzles from a benchmark taken before serving requests
+    puzzleRelease, err := strconv.ParseBool(GetEnvDefault("PUZZLE_RELEASE", "true"))
+    if err != nil {
+        log.Fatalf("Failed to parse PUZZLE_RELEASE: %v", err)
+    }
+    if puzzleRelease {
+        if err := calibratePuzzles(); err != nil {
+            log.Fatalf("Failed to calibrate time-lock puzzles: %v", err)
+        }
     }
 
-    privKeys = make(map[string][]byte)
     encryptedData = make(map[string][]byte)
 
     // Set up distributed testing configuration
     testConfig := t.TestConfig{
-        NetworkEndpoint: "wss://api.avax-test.network/ext/bc/C/ws",
+        NetworkEndpoint: network.Endpoints[0],
         ContractAddress: contractAddress,
-        ContractABI:     contractABI,
         NetworkConditions: []t.NetworkCondition{
             {
                 BaseLatency: 100 * time.Millisecond,
@@ -139,20 +235,160 @@ func main() {
     ctx, cancel := context.WithCancel(context.Background())
     defer cancel()
 
+    client.Start(ctx)
+
     if err := distributor.StartEventMonitoring(ctx); err != nil {
         log.Fatalf("Failed to start event monitoring: %v", err)
     }
 
+    // Index every contract event since deployment, then follow new blocks
+    eventConfirmations, err := strconv.ParseUint(GetEnvDefault("EVENT_CONFIRMATIONS", "3"), 10, 64)
+    if err != nil {
+        log.Fatalf("Failed to parse EVENT_CONFIRMATIONS: %v", err)
+    }
+    events, err = indexer.NewIndexer(indexer.IndexerConfig{
+        Client:          client,
+        Contract:        contract,
+        ContractAddress: contractAddress,
+        Path:            GetEnvDefault("INDEX_PATH", "events.db"),
+        StartBlock:      network.DeploymentBlock,
+        Confirmations:   eventConfirmations,
+        OnConfirmed:     onEventConfirmed,
+        OnRemoved:       onEventRemoved,
+    })
+    if err != nil {
+        log.Fatalf("Failed to open event index: %v", err)
+    }
+    defer events.Close()
+
+    // Keep a searchable copy of every record for /records
+    catalog, err = records.NewCatalog(records.CatalogConfig{
+        Client:   client,
+        Contract: contract,
+        Path:     GetEnvDefault("RECORDS_PATH", "records.db"),
+    })
+    if err != nil {
+        log.Fatalf("Failed to open record index: %v", err)
+    }
+    defer catalog.Close()
+
+    // Publish each stored key once the chain passes its release time
+    releaser, err = r.NewScheduler(r.SchedulerConfig{
+        Client:          client,
+        Contract:        contract,
+        Signer:          txSigner,
+        ChainID:         chainID,
+        GasStrategy:     gasStrategy,
+        Nonces:          nonces,
+        Transactions:    transactions,
+        KeyLookup:       lookupReleaseKey,
+        Path:            GetEnvDefault("RELEASES_PATH", "releases.db"),
+    })
+    if err != nil {
+        log.Fatalf("Failed to initialize release scheduler: %v", err)
+    }
+    defer releaser.Close()
+    releaser.Start(ctx)
+
+    // Stand in for Chainlink Automation on chains that do not have it
+    runUpkeep, err := strconv.ParseBool(GetEnvDefault("UPKEEP", strconv.FormatBool(network.Upkeep)))
+    if err != nil {
+        log.Fatalf("Failed to parse UPKEEP: %v", err)
+    }
+    if runUpkeep {
+        keeper, err = upkeep.NewRunner(upkeep.RunnerConfig{
+            Client:       client,
+            Contract:     contract,
+            Signer:       txSigner,
+            ChainID:      chainID,
+            GasStrategy:  gasStrategy,
+            Nonces:       nonces,
+            Transactions: transactions,
+        })
+        if err != nil {
+            log.Fatalf("Failed to initialize upkeep runner: %v", err)
+        }
+        keeper.Start(ctx)
+        log.Printf("Performing upkeep for %s", contractAddress.Hex())
+    }
+
+    // Follow upload transactions so clients can poll instead of blocking
+    confirmations, err := strconv.ParseUint(GetEnvDefault("JOB_CONFIRMATIONS", "3"), 10, 64)
+    if err != nil {
+        log.Fatalf("Failed to parse JOB_CONFIRMATIONS: %v", err)
+    }
+    uploads, err = jobs.NewTracker(jobs.TrackerConfig{
+        Client:        client,
+        Path:          GetEnvDefault("JOBS_PATH", "jobs.db"),
+        Confirmations: confirmations,
+        OnConfirmed:   onUploadConfirmed,
+    })
+    if err != nil {
+        log.Fatalf("Failed to initialize upload jobs: %v", err)
+    }
+    defer uploads.Close()
+    uploads.Start(ctx)
+
+    // Re-send transactions that sit unmined with higher fees
+    transactions.Start(ctx)
+
+    // Fill nonces that failed sends left behind so later transactions can mine
+    nonces.Start(ctx, 30*time.Second, 2*time.Minute, fillNonceGap)
+
+    // Check every published key against its commitment and ciphertext
+    verifier, err = v.NewVerifier(v.VerifierConfig{
+        Client:      client,
+        Contract:    contract,
+        FetchRecord: fetchPublicData,
+        Path:        GetEnvDefault("VERIFICATIONS_PATH", "verifications.db"),
+        StartBlock:  network.DeploymentBlock,
+    })
+    if err != nil {
+        log.Fatalf("Failed to initialize key verifier: %v", err)
+    }
+    defer verifier.Close()
+    if err := verifier.Start(ctx); err != nil {
+        log.Fatalf("Failed to start key verifier: %v", err)
+    }
+
+    // The indexer's callbacks feed the releaser, verifier and catalog, so start it last
+    catalog.Start(ctx)
+    events.Start(ctx)
+
     // Remove the separate Web3Listener
     // go Web3Listener()
 
     router := gin.Default()
     router.POST("/upload", postData)
+    router.POST("/upload/batch", postDataBatch)
+    router.GET("/jobs/:id", getJob)
     router.GET("/get/:dataname/:owner", getData)
+    router.GET("/decrypt/:dataname/:owner", decryptData)
+    router.GET("/puzzle/:dataname/:owner", getPuzzle)
+    router.GET("/blobs/:digest", getBlob)
     router.GET("/stats", getTestingStats)
-
-    fmt.Println("Server is running on port 8080")
-    router.Run(":8080")
+    router.GET("/endpoints", getEndpoints)
+    router.GET("/releases", getReleases)
+    router.GET("/releases/:dataname/:owner", getRelease)
+    router.GET("/upkeep", getUpkeep)
+    router.GET("/records", getRecords)
+    router.GET("/records/status", getRecordsStatus)
+    router.GET("/events", getEvents)
+    router.GET("/events/status", getIndexStatus)
+    router.GET("/reorgs", getReorgs)
+    router.GET("/verifications", getVerifications)
+    router.GET("/verifications/:dataname/:owner", getVerification)
+    router.POST("/custody/shares", postShare)
+    router.GET("/custody/:dataname/:owner", getCustodyStatus)
+
+    admin := router.Group("/admin", adminAuth)
+    admin.GET("/transactions", getPendingTransactions)
+    admin.POST("/transactions/:hash/speedup", speedUpTransaction)
+    admin.POST("/transactions/:hash/cancel", cancelTransaction)
+
+    port := GetEnvDefault("PORT", "8080")
+    fmt.Printf("Server is running on port %s\n", port)
+    router.Run(":" + port)
 }
 
 func getTestingStats(c *gin.Context) {
@@ -173,6 +409,10 @@ func getTestingStats(c *gin.Context) {
     c.JSON(200, formattedStats)
 }
 
+func getEndpoints(c *gin.Context) {
+    c.JSON(200, client.Status())
+}
+
 func formatTimings(timings map[int]time.Time) map[string]string {
     formatted := make(map[string]string)
     for nodeID, timing := range timings {
@@ -182,28 +422,28 @@ func formatTimings(timings map[int]time.Time) map[string]string {
 }
 
 func postData(c *gin.Context) {
-    data := c.PostForm("data")
     owner := c.PostForm("owner")
     dataName := c.PostForm("dataname")
     releaseTime := c.PostForm("releaseTime")
 
-    // Encrypt the data first
-    encryptedData, privKey, err := h.EncryptData(data)
+    ReleaseTime, err := strconv.ParseUint(releaseTime, 10, 64)
     if err != nil {
-        c.JSON(400, gin.H{"error": fmt.Sprintf("Failed to encrypt data: %v", err)})
+        c.JSON(400, gin.H{"error": fmt.Sprintf("Failed to convert release time to uint64: %v", err)})
         return
     }
 
-    privKeys[dataName] = privKey
-
-    // Calculate hash from the encrypted data
-    hash := sha256.Sum256(encryptedData)
-
-    ReleaseTime, err := strconv.ParseUint(releaseTime, 10, 64)
+    // Callers that encrypted locally send the ciphertext instead of data
+    var sealed sealedUpload
+    if c.PostForm("encryptedData") != "" {
+        sealed, err = parseClientSealed(c)
+    } else {
+        sealed, err = sealOnServer(c, ReleaseTime)
+    }
     if err != nil {
-        c.JSON(400, gin.H{"error": fmt.Sprintf("Failed to convert release time to uint64: %v", err)})
+        c.JSON(400, gin.H{"error": err.Error()})
         return
     }
+    encryptedData, hash, privKey := sealed.EncryptedData, sealed.Hash, sealed.PrivateKey
 
     // Validate input parameters
     if len(encryptedData) == 0 {
@@ -223,117 +463,260 @@ func postData(c *gin.Context) {
         return
     }
 
-    privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(PrivateKey, "0x"))
+    storage := c.DefaultPostForm("storage", storageChain)
+    payload, blob, err := onChainPayload(storage, encryptedData)
     if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to parse private key: %v", err)})
+        c.JSON(400, gin.H{"error": err.Error()})
         return
     }
 
-    auth, err := bind.NewKeyedTransactorWithChainID(privateKey, big.NewInt(43113))
-    if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to create authenticated transactor: %v", err)})
-        return
+    ctx := context.Background()
+
+    // Callers may pick a different gas strategy per upload
+    strategy := gasStrategy
+    if name := c.PostForm("gasStrategy"); name != "" {
+        strategy, err = txmgr.StrategyByName(name, gasMaxFeeCap)
+        if err != nil {
+            c.JSON(400, gin.H{"error": err.Error()})
+            return
+        }
     }
 
-    input, err := contractABI.Pack("addStoredData",
-        encryptedData,
-        owner,
-        dataName,
-        big.NewInt(int64(ReleaseTime)),
-        hash[:],
-    )
+    fees, err := strategy.Fees(ctx, client)
     if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to pack transaction data: %v", err)})
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to price transaction: %v", err)})
         return
     }
 
-    gasPrice, err := client.SuggestGasPrice(context.Background())
+    job, err := submitUpload(ctx, fees, owner, dataName, ReleaseTime, payload, blob, hash, len(privKey) > 0)
     if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to suggest gas price: %v", err)})
+        c.JSON(500, gin.H{"error": err.Error()})
         return
     }
 
-    gasLimit, err := client.EstimateGas(context.Background(), ethereum.CallMsg{
-        From:     auth.From,
-        To:       &contractAddress,
-        Gas:      0,
-        GasPrice: gasPrice,
-        Value:    big.NewInt(0),
-        Data:     input,
+    // Escrow the key only once the record's transaction is out, so a failed
+    // or duplicate upload cannot replace the key of a live record
+    if len(privKey) > 0 {
+        if job, err = escrowReleaseKey(job, privKey); err != nil {
+            c.JSON(500, gin.H{
+                "error":           err.Error(),
+                "jobId":           job.ID,
+                "transactionHash": job.TxHash,
+            })
+            return
+        }
+    }
+
+    c.JSON(202, gin.H{
+        "message":         "Transaction submitted",
+        "jobId":           job.ID,
+        "transactionHash": job.TxHash,
+        "fees":            fees,
+        "cipher":          sealed.Cipher,
+        "mode":            sealed.Mode,
+        "storage":         storage,
+        "keyEscrowed":     job.KeyEscrowed,
+        "timeLocked":      sealed.Squarings > 0,
     })
+}
+
+// onUploadConfirmed schedules the key release once the record is on-chain.
+// Without a key the caller is responsible for releasing it themselves. The
+// job stays active until the release is scheduled.
+func onUploadConfirmed(job jobs.Job) error {
+    if job.KeyEscrowed {
+        if err := releaser.Track(job.Owner, job.DataName, job.ReleaseTime); err != nil {
+            return err
+        }
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    if err := catalog.Refresh(ctx, job.Owner, job.DataName); err != nil {
+        log.Printf("Failed to index record %s/%s: %v", job.Owner, job.DataName, err)
+    }
+    return nil
+}
+
+func getJob(c *gin.Context) {
+    job, err := uploads.Get(c.Param("id"))
+    if errors.Is(err, jobs.ErrJobNotFound) {
+        c.JSON(404, gin.H{"error": "Job not found"})
+        return
+    }
     if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to estimate gas limit: %v", err)})
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to load job: %v", err)})
         return
     }
 
-    gasLimit = uint64(float64(gasLimit) * 1.1)
+    c.JSON(200, job)
+}
 
-    nonce, err := client.PendingNonceAt(context.Background(), auth.From)
-    if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to retrieve account nonce: %v", err)})
+func getReleases(c *gin.Context) {
+    c.JSON(200, releaser.Records())
+}
+
+func getRelease(c *gin.Context) {
+    record, exists := releaser.Get(c.Param("owner"), c.Param("dataname"))
+    if !exists {
+        c.JSON(404, gin.H{"error": "No release scheduled for this record"})
         return
     }
 
-    tx := types.NewTransaction(nonce, contractAddress, big.NewInt(0), gasLimit, gasPrice, input)
+    c.JSON(200, record)
+}
 
-    signedTx, err := types.SignTx(tx, types.NewEIP155Signer(big.NewInt(43113)), privateKey)
+func getUpkeep(c *gin.Context) {
+    if keeper == nil {
+        c.JSON(404, gin.H{"error": "Upkeep runner is not enabled for this network"})
+        return
+    }
+
+    c.JSON(200, keeper.Metrics())
+}
+
+func getEvents(c *gin.Context) {
+    list, err := events.Events(indexer.Filter{
+        Name:          c.Query("name"),
+        Owner:         c.Query("owner"),
+        DataName:      c.Query("dataname"),
+        ConfirmedOnly: c.Query("confirmed") == "true",
+    })
     if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to sign transaction: %v", err)})
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to query events: %v", err)})
+        return
+    }
+
+    c.JSON(200, list)
+}
+
+func getRecords(c *gin.Context) {
+    query := records.Query{
+        Owner:      c.Query("owner"),
+        Sort:       c.Query("sort"),
+        Descending: c.Query("order") == "desc",
+        Cursor:     c.Query("cursor"),
+    }
+    if order := c.Query("order"); order != "" && order != "asc" && order != "desc" {
+        c.JSON(400, gin.H{"error": "Order must be asc or desc"})
         return
     }
+    if phase := c.Query("phase"); phase != "" {
+        value, err := strconv.ParseInt(phase, 10, 64)
+        if err != nil || value < 0 || value > 2 {
+            c.JSON(400, gin.H{"error": "Phase must be 0, 1 or 2"})
+            return
+        }
+        query.Phase = &value
+    }
+    if keyReleased := c.Query("keyReleased"); keyReleased != "" {
+        value, err := strconv.ParseBool(keyReleased)
+        if err != nil {
+            c.JSON(400, gin.H{"error": "keyReleased must be true or false"})
+            return
+        }
+        query.KeyReleased = &value
+    }
+    for param, target := range map[string]*uint64{"releaseFrom": &query.ReleaseFrom, "releaseTo": &query.ReleaseTo} {
+        if value := c.Query(param); value != "" {
+            parsed, err := strconv.ParseUint(value, 10, 64)
+            if err != nil {
+                c.JSON(400, gin.H{"error": fmt.Sprintf("%s must be a unix timestamp", param)})
+                return
+            }
+            *target = parsed
+        }
+    }
+    if limit := c.Query("limit"); limit != "" {
+        value, err := strconv.Atoi(limit)
+        if err != nil || value <= 0 {
+            c.JSON(400, gin.H{"error": "Limit must be a positive integer"})
+            return
+        }
+        query.Limit = value
+    }
 
-    err = client.SendTransaction(context.Background(), signedTx)
+    page, err := catalog.List(query)
+    if errors.Is(err, records.ErrInvalidCursor) || errors.Is(err, records.ErrInvalidSort) {
+        c.JSON(400, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to send transaction: %v", err)})
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to list records: %v", err)})
         return
     }
 
-    receipt, err := bind.WaitMined(context.Background(), client, signedTx)
+    c.JSON(200, page)
+}
+
+func getRecordsStatus(c *gin.Context) {
+    status, err := catalog.Status()
     if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to get transaction receipt: %v", err)})
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to load record index status: %v", err)})
         return
     }
 
+    c.JSON(200, status)
+}
+
+func getIndexStatus(c *gin.Context) {
+    c.JSON(200, events.Status())
+}
+
+func getReorgs(c *gin.Context) {
+    nodes := make(map[string]interface{})
+    for nodeID, stats := range distributor.GetReorgStats() {
+        nodes[fmt.Sprintf("node_%d", nodeID)] = stats
+    }
+
     c.JSON(200, gin.H{
-        "message":         "Data published successfully",
-        "transactionHash": receipt.TxHash.Hex(),
-        "blockNumber":     receipt.BlockNumber.Uint64(),
+        "indexer": events.Reorgs(),
+        "nodes":   nodes,
     })
 }
 
-func getData(c *gin.Context) {
-    dataName := c.Param("dataname")
-    owner := c.Param("owner")
-
-    input, err := contractABI.Pack("GetPublicData", dataName, owner)
-    if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to pack transaction data: %v", err)})
-        return
+// onEventConfirmed treats a key release as final once it is deep enough
+func onEventConfirmed(event indexer.Event) {
+    if err := catalog.Apply(event); err != nil {
+        log.Printf("Failed to index %s for %s/%s: %v", event.Name, event.Owner, event.DataName, err)
+    }
+    if event.Name == indexer.EventKeyReleased && len(event.PrivateKey) > 0 {
+        releaser.Confirm(event.Owner, event.DataName, event.TxHash.Hex())
     }
+}
 
-    msg := ethereum.CallMsg{
-        To:   &contractAddress,
-        Data: input,
+// onEventRemoved rolls back state derived from an event a reorg dropped
+func onEventRemoved(event indexer.Event) {
+    if err := catalog.Revert(event); err != nil {
+        log.Printf("Failed to revert %s for %s/%s: %v", event.Name, event.Owner, event.DataName, err)
     }
+    if event.Name == indexer.EventKeyReleased && len(event.PrivateKey) > 0 {
+        releaser.Rollback(event.Owner, event.DataName, event.TxHash.Hex())
+        verifier.Forget(event.Owner, event.DataName, event.TxHash.Hex())
+    }
+}
 
-    output, err := client.CallContract(context.Background(), msg, nil)
-    if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to call contract: %v", err)})
+func getVerifications(c *gin.Context) {
+    c.JSON(200, verifier.Results(c.Query("mismatches") == "true"))
+}
+
+func getVerification(c *gin.Context) {
+    result, exists := verifier.Get(c.Param("owner"), c.Param("dataname"))
+    if !exists {
+        c.JSON(404, gin.H{"error": "No released key has been verified for this record"})
         return
     }
 
-    var result struct {
-        EncryptedData []byte
-        Hash          []byte
-        Owner         string
-        DataName      string
-        ReleaseTime   *big.Int
-        KeyReleased   bool
-    }
+    c.JSON(200, result)
+}
 
-    err = contractABI.UnpackIntoInterface(&result, "GetPublicData", output)
+func getData(c *gin.Context) {
+    dataName := c.Param("dataname")
+    owner := c.Param("owner")
+
+    result, err := fetchPublicData(context.Background(), dataName, owner)
     if err != nil {
-        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to unpack output: %v", err)})
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to fetch data: %v", err)})
         return
     }
 
@@ -345,10 +728,113 @@ func getData(c *gin.Context) {
         "releaseTime":   result.ReleaseTime.String(),
         "keyReleased":   result.KeyReleased,
     }
+    if _, commitment := h.SplitRecordHash(result.Hash); commitment != nil {
+        response["keyCommitment"] = hexutil.Encode(commitment)
+    }
+    if result.Locator != "" {
+        response["storage"] = storageBlob
+        response["locator"] = result.Locator
+    }
 
     c.JSON(200, response)
 }
 
+// fillNonceGap spends an abandoned nonce with a zero-value self-transfer
+func fillNonceGap(ctx context.Context, nonce uint64) error {
+    fees, err := gasStrategy.Fees(ctx, client)
+    if err != nil {
+        return err
+    }
+    _, err = txmgr.SelfTransfer(ctx, client, txSigner, chainID, nonce, fees)
+    return err
+}
+
+// fetchPublicData calls GetPublicData through the contract bindings
+func fetchPublicData(ctx context.Context, dataName, owner string) (h.PublicData, error) {
+    encryptedData, hash, recordOwner, recordName, releaseTime, keyReleased, err :=
+        contract.GetPublicData(&bind.CallOpts{Context: ctx}, dataName, owner)
+    if err != nil {
+        return h.PublicData{}, fmt.Errorf("failed to call contract: %v", err)
+    }
+
+    record := h.PublicData{
+        EncryptedData: encryptedData,
+        Hash:          hash,
+        Owner:         recordOwner,
+        DataName:      recordName,
+        ReleaseTime:   releaseTime,
+        KeyReleased:   keyReleased,
+    }
+    if blobstore.IsLocator(encryptedData) {
+        return resolveBlob(ctx, record)
+    }
+    return record, nil
+}
+
+// resolveBlob swaps an on-chain locator for the ciphertext it points to,
+// checked against both the locator digest and the on-chain record hash.
+func resolveBlob(ctx context.Context, record h.PublicData) (h.PublicData, error) {
+    locator, err := blobstore.ParseLocator(record.EncryptedData)
+    if err != nil {
+        return h.PublicData{}, err
+    }
+
+    blob, err := blobFetcher.Fetch(ctx, locator)
+    if err != nil {
+        return h.PublicData{}, fmt.Errorf("failed to fetch blob %s: %w", locator.Hex(), err)
+    }
+    if !h.VerifyCiphertextHash(blob, record.Hash) {
+        return h.PublicData{}, fmt.Errorf("blob %s: %w", locator.Hex(), blobstore.ErrDigestMismatch)
+    }
+
+    record.EncryptedData = blob
+    record.Locator = locator.URL
+    return record, nil
+}
+
+// newBlobFetcher follows locators only to this server's BLOB_PUBLIC_URL and
+// the comma-separated BLOB_ALLOWED_HOSTS, reading at most BLOB_MAX_SIZE bytes
+func newBlobFetcher(local blobstore.Store, publicURL, allowedHosts string) (*blobstore.Fetcher, error) {
+    public, err := url.Parse(publicURL)
+    if err != nil || public.Host == "" {
+        return nil, fmt.Errorf("invalid BLOB_PUBLIC_URL %q", publicURL)
+    }
+    hosts := []string{public.Host}
+    for _, host := range strings.Split(allowedHosts, ",") {
+        if host = strings.TrimSpace(host); host != "" {
+            hosts = append(hosts, host)
+        }
+    }
+
+    fetcher := blobstore.NewFetcher(local, hosts)
+    maxSize, err := strconv.ParseInt(GetEnvDefault("BLOB_MAX_SIZE", strconv.Itoa(blobstore.DefaultMaxSize)), 10, 64)
+    if err != nil || maxSize <= 0 {
+        return nil, errors.New("BLOB_MAX_SIZE must be a positive byte count")
+    }
+    fetcher.MaxSize = maxSize
+    return fetcher, nil
+}
+
+func getBlob(c *gin.Context) {
+    digest, err := blobstore.ParseDigest(c.Param("digest"))
+    if err != nil {
+        c.JSON(400, gin.H{"error": err.Error()})
+        return
+    }
+
+    blob, err := blobs.Get(digest)
+    if errors.Is(err, blobstore.ErrBlobNotFound) {
+        c.JSON(404, gin.H{"error": "Blob not found"})
+        return
+    }
+    if err != nil {
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to read blob: %v", err)})
+        return
+    }
+
+    c.Data(200, "application/octet-stream", blob)
+}
+
 // Remove the Web3Listener function
 /*
 func Web3Listener() {
@@ -375,6 +861,13 @@ func HandleKeyReleaseRequestedEvent(vLog types.Log) {
 }
 */
 
+func GetEnvDefault(key, fallback string) string {
+    if value := os.Getenv(key); value != "" {
+        return value
+    }
+    return fallback
+}
+
 func MustGetEnv(key string) string {
     value := os.Getenv(key)
     if value == "" {
@@ -0,0 +1,157 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "log"
+    "math"
+    "net/http"
+    "net/url"
+    "os"
+    "time"
+
+    h "web3server/helper"
+
+    "github.com/ethereum/go-ethereum/common/hexutil"
+    "github.com/gin-gonic/gin"
+)
+
+// squaringRate is how many puzzle squarings this machine does per second,
+// measured at startup. It stays 0 when time-locked release is disabled.
+var squaringRate uint64
+
+// calibratePuzzles benchmarks this machine once so puzzle requests never
+// wait on the calibration
+func calibratePuzzles() error {
+    rate, err := h.CalibrateSquarings(time.Second)
+    if err != nil {
+        return err
+    }
+    squaringRate = rate
+    log.Printf("Calibrated time-lock puzzles at %d squarings/s", squaringRate)
+    return nil
+}
+
+// maxPuzzleDelay bounds how far ahead a time-locked release can be. Beyond a
+// few years the calibration says little about the hardware that will solve it.
+const maxPuzzleDelay = 10 * 365 * 24 * 60 * 60
+
+// puzzleSquarings sizes a puzzle so it takes until releaseTime to solve
+func puzzleSquarings(releaseTime uint64) (uint64, error) {
+    rate := squaringRate
+    if rate == 0 {
+        return 0, errors.New("Time-locked release is disabled on this server")
+    }
+
+    now := uint64(time.Now().Unix())
+    if releaseTime <= now {
+        return 0, errors.New("Release time must be in the future")
+    }
+    delay := releaseTime - now
+    if delay > maxPuzzleDelay {
+        return 0, fmt.Errorf("Time-locked release time must be within %d days", maxPuzzleDelay/(24*60*60))
+    }
+    // A wrapped product would give a puzzle that solves almost instantly
+    if rate > 0 && delay > math.MaxUint64/rate {
+        return 0, errors.New("Release time is too far ahead for a time-lock puzzle on this machine")
+    }
+    return delay * rate, nil
+}
+
+// getPuzzle reports the time-lock puzzle parameters of a record so clients
+// can solve it themselves instead of waiting for releaseKey.
+func getPuzzle(c *gin.Context) {
+    dataName := c.Param("dataname")
+    owner := c.Param("owner")
+
+    record, err := fetchPublicData(context.Background(), dataName, owner)
+    if err != nil {
+        c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to fetch data: %v", err)})
+        return
+    }
+    if record.ReleaseTime.Sign() == 0 {
+        c.JSON(404, gin.H{"error": "Record not found"})
+        return
+    }
+
+    puzzle, err := h.EnvelopePuzzle(record.EncryptedData)
+    if err != nil || puzzle == nil {
+        c.JSON(404, gin.H{"error": "Record is not time-locked"})
+        return
+    }
+
+    response := gin.H{
+        "n":           hexutil.EncodeBig(puzzle.N),
+        "a":           hexutil.EncodeBig(puzzle.A),
+        "t":           puzzle.T,
+        "lockedKey":   hexutil.Encode(puzzle.LockedKey),
+        "releaseTime": record.ReleaseTime.Uint64(),
+    }
+    if squaringRate > 0 {
+        response["squaringsPerSecond"] = squaringRate
+        response["estimatedSeconds"] = puzzle.T / squaringRate
+    }
+
+    c.JSON(200, response)
+}
+
+// runSolve is the "solve" command: it fetches a time-locked record (or
+// takes its ciphertext directly) and decrypts it by solving the puzzle.
+func runSolve(args []string) {
+    fs := flag.NewFlagSet("solve", flag.ExitOnError)
+    api := fs.String("api", "http://localhost:8080", "backend to fetch the record from")
+    dataName := fs.String("dataname", "", "name of the record")
+    owner := fs.String("owner", "", "owner of the record")
+    ciphertext := fs.String("ciphertext", "", "0x-prefixed ciphertext, instead of fetching the record")
+    fs.Parse(args)
+
+    encryptedData, err := solveInput(*api, *dataName, *owner, *ciphertext)
+    if err != nil {
+        log.Fatalf("Failed to load ciphertext: %v", err)
+    }
+
+    puzzle, err := h.EnvelopePuzzle(encryptedData)
+    if err != nil || puzzle == nil {
+        log.Fatalf("Ciphertext is not time-locked")
+    }
+    log.Printf("Solving puzzle with %d squarings", puzzle.T)
+
+    start := time.Now()
+    plaintext, err := h.SolveEnvelope(encryptedData)
+    if err != nil {
+        log.Fatalf("Failed to solve puzzle: %v", err)
+    }
+    log.Printf("Solved in %v", time.Since(start))
+
+    fmt.Fprintln(os.Stdout, string(plaintext))
+}
+
+func solveInput(api, dataName, owner, ciphertext string) ([]byte, error) {
+    if ciphertext != "" {
+        return hexutil.Decode(ciphertext)
+    }
+    if dataName == "" || owner == "" {
+        return nil, fmt.Errorf("either -ciphertext or both -dataname and -owner are required")
+    }
+
+    resp, err := http.Get(fmt.Sprintf("%s/get/%s/%s", api, url.PathEscape(dataName), url.PathEscape(owner)))
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    var record struct {
+        EncryptedData string `json:"encryptedData"`
+        Error         string `json:"error"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+        return nil, err
+    }
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("backend returned %d: %s", resp.StatusCode, record.Error)
+    }
+    return hexutil.Decode(record.EncryptedData)
+}
@@ -0,0 +1,506 @@
+package records
+
+import (
+    "context"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "math/big"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "web3server/bindings"
+    "web3server/blobstore"
+    "web3server/indexer"
+
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/common/hexutil"
+    bolt "go.etcd.io/bbolt"
+)
+
+const (
+    SortReleaseTime = "releaseTime"
+    SortOwner       = "owner"
+    SortDataName    = "dataName"
+
+    DefaultLimit = 50
+    MaxLimit     = 500
+)
+
+var (
+    ErrInvalidCursor = errors.New("invalid cursor")
+    ErrInvalidSort   = errors.New("invalid sort field")
+)
+
+var (
+    recordsBucket = []byte("records")
+    metaBucket    = []byte("meta")
+    syncKey       = []byte("lastSync")
+)
+
+// Record is the searchable summary of one stored record; the ciphertext
+// itself stays on chain and is read through /get.
+type Record struct {
+    Owner       string        `json:"owner"`
+    DataName    string        `json:"dataName"`
+    ReleaseTime uint64        `json:"releaseTime"`
+    Phase       int64         `json:"phase"`
+    KeyReleased bool          `json:"keyReleased"`
+    Hash        hexutil.Bytes `json:"hash"`
+    Size        int           `json:"size"`
+    Blob        bool          `json:"blob"`
+    UpdatedAt   time.Time     `json:"updatedAt"`
+}
+
+func (r Record) id() string {
+    return r.Owner + "\x00" + r.DataName
+}
+
+// Query narrows and orders a record listing; zero values match everything
+type Query struct {
+    Owner       string
+    Phase       *int64
+    KeyReleased *bool
+    ReleaseFrom uint64
+    ReleaseTo   uint64
+    Sort        string
+    Descending  bool
+    Cursor      string
+    Limit       int
+}
+
+// Page is one slice of a listing and the cursor for the next one
+type Page struct {
+    Records    []Record `json:"records"`
+    Total      int      `json:"total"`
+    NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// SyncStatus describes the last full read of returnStoredData
+type SyncStatus struct {
+    Block    uint64    `json:"block"`
+    Records  int       `json:"records"`
+    SyncedAt time.Time `json:"syncedAt"`
+}
+
+// Backend is the part of a client needed to pin a sync to one block
+type Backend interface {
+    BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// CatalogConfig holds the contract to index and where to keep the index
+type CatalogConfig struct {
+    Client       Backend
+    Contract     *bindings.TwoPhaseCommit
+    Path         string
+    SyncInterval time.Duration
+}
+
+// Catalog keeps a local, searchable copy of the contract's stored records.
+// It is loaded from returnStoredData on an interval and kept current in
+// between from confirmed uploads and contract events, so listing never
+// has to call the unbounded returnStoredData itself.
+type Catalog struct {
+    Config CatalogConfig
+    db     *bolt.DB
+    mu     sync.Mutex
+    wg     sync.WaitGroup
+    logger *log.Logger
+}
+
+// NewCatalog opens (or creates) the record index at config.Path
+func NewCatalog(config CatalogConfig) (*Catalog, error) {
+    if config.Client == nil || config.Contract == nil {
+        return nil, errors.New("catalog requires a client and contract")
+    }
+    if config.Path == "" {
+        config.Path = "records.db"
+    }
+    if config.SyncInterval == 0 {
+        config.SyncInterval = 5 * time.Minute
+    }
+
+    db, err := bolt.Open(config.Path, 0600, &bolt.Options{Timeout: time.Second})
+    if err != nil {
+        return nil, fmt.Errorf("failed to open record index %s: %w", config.Path, err)
+    }
+    err = db.Update(func(tx *bolt.Tx) error {
+        for _, name := range [][]byte{recordsBucket, metaBucket} {
+            if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to initialize record index: %w", err)
+    }
+
+    return &Catalog{
+        Config: config,
+        db:     db,
+        logger: log.New(os.Stdout, "[Records] ", log.LstdFlags|log.Lmicroseconds),
+    }, nil
+}
+
+// Start syncs the index now and then on every SyncInterval
+func (c *Catalog) Start(ctx context.Context) {
+    c.wg.Add(1)
+    go func() {
+        defer c.wg.Done()
+
+        ticker := time.NewTicker(c.Config.SyncInterval)
+        defer ticker.Stop()
+
+        for {
+            if err := c.Sync(ctx); err != nil && ctx.Err() == nil {
+                c.logger.Printf("Failed to sync records: %v", err)
+            }
+
+            select {
+            case <-ticker.C:
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+}
+
+// Sync reloads every record from returnStoredData at the current head.
+// Records missing on chain are dropped unless they were added after the
+// call started. For records that are still the
+// same upload, phase and key release only move forward, so an event
+// applied while the call was in flight is not undone.
+func (c *Catalog) Sync(ctx context.Context) error {
+    started := time.Now()
+    head, err := c.Config.Client.BlockNumber(ctx)
+    if err != nil {
+        return fmt.Errorf("failed to retrieve head block: %w", err)
+    }
+    stored, err := c.Config.Contract.ReturnStoredData(&bind.CallOpts{
+        Context:     ctx,
+        BlockNumber: new(big.Int).SetUint64(head),
+    })
+    if err != nil {
+        return fmt.Errorf("failed to call returnStoredData: %w", err)
+    }
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    now := time.Now()
+    err = c.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(recordsBucket)
+
+        seen := make(map[string]bool, len(stored))
+        for _, data := range stored {
+            record := Record{
+                Owner:       data.Owner,
+                DataName:    data.DataName,
+                ReleaseTime: data.ReleaseTime.Uint64(),
+                Phase:       data.Phase.Int64(),
+                KeyReleased: data.KeyReleased,
+                Hash:        data.Hash,
+                Size:        len(data.EncryptedData),
+                Blob:        blobstore.IsLocator(data.EncryptedData),
+                UpdatedAt:   now,
+            }
+            if existing, ok := get(bucket, record.id()); ok && string(existing.Hash) == string(record.Hash) {
+                if existing.Phase > record.Phase {
+                    record.Phase = existing.Phase
+                }
+                record.KeyReleased = record.KeyReleased || existing.KeyReleased
+            }
+            seen[record.id()] = true
+            if err := put(bucket, record); err != nil {
+                return err
+            }
+        }
+
+        var stale [][]byte
+        err := bucket.ForEach(func(key, value []byte) error {
+            if seen[string(key)] {
+                return nil
+            }
+            var record Record
+            if err := json.Unmarshal(value, &record); err == nil && record.UpdatedAt.After(started) {
+                return nil
+            }
+            stale = append(stale, append([]byte{}, key...))
+            return nil
+        })
+        if err != nil {
+            return err
+        }
+        for _, key := range stale {
+            if err := bucket.Delete(key); err != nil {
+                return err
+            }
+        }
+
+        status, err := json.Marshal(SyncStatus{Block: head, Records: len(stored), SyncedAt: now})
+        if err != nil {
+            return err
+        }
+        return tx.Bucket(metaBucket).Put(syncKey, status)
+    })
+    if err != nil {
+        return fmt.Errorf("failed to store records: %w", err)
+    }
+    return nil
+}
+
+// Refresh loads a single record through GetPublicData, used once an upload
+// is confirmed so it is listed before the next full sync.
+func (c *Catalog) Refresh(ctx context.Context, owner, dataName string) error {
+    encryptedData, hash, _, _, releaseTime, keyReleased, err := c.Config.Contract.GetPublicData(&bind.CallOpts{Context: ctx}, dataName, owner)
+    if err != nil {
+        return fmt.Errorf("failed to call GetPublicData: %w", err)
+    }
+    if releaseTime == nil || releaseTime.Sign() == 0 {
+        return nil
+    }
+
+    return c.update(owner, dataName, func(r *Record, exists bool) bool {
+        if exists && string(r.Hash) != string(hash) {
+            r.Phase = 0
+        }
+        r.ReleaseTime = releaseTime.Uint64()
+        r.Hash = hash
+        r.Size = len(encryptedData)
+        r.Blob = blobstore.IsLocator(encryptedData)
+        r.KeyReleased = r.KeyReleased || keyReleased
+        return true
+    })
+}
+
+// Apply advances a record from a confirmed contract event
+func (c *Catalog) Apply(event indexer.Event) error {
+    return c.update(event.Owner, event.DataName, func(r *Record, exists bool) bool {
+        switch {
+        case event.Name == indexer.EventReleaseEncryptedData:
+            r.ReleaseTime = event.ReleaseTime
+            r.Hash = event.Hash
+            if r.Phase < 1 {
+                r.Phase = 1
+            }
+            return true
+        case !exists:
+            // Other events do not carry enough to create a record
+            return false
+        case event.Name == indexer.EventKeyReleased && len(event.PrivateKey) == 0:
+            if r.Phase < 2 {
+                r.Phase = 2
+            }
+            return true
+        case event.Name == indexer.EventKeyReleased:
+            r.KeyReleased = true
+            return true
+        }
+        return false
+    })
+}
+
+// Revert undoes what Apply did for an event a reorg removed
+func (c *Catalog) Revert(event indexer.Event) error {
+    return c.update(event.Owner, event.DataName, func(r *Record, exists bool) bool {
+        if !exists {
+            return false
+        }
+        switch {
+        case event.Name == indexer.EventReleaseEncryptedData && r.Phase == 1:
+            r.Phase = 0
+        case event.Name == indexer.EventKeyReleased && len(event.PrivateKey) == 0 && r.Phase == 2:
+            r.Phase = 1
+        case event.Name == indexer.EventKeyReleased && len(event.PrivateKey) > 0:
+            r.KeyReleased = false
+        default:
+            return false
+        }
+        return true
+    })
+}
+
+// update applies fn to a record, creating it if fn asks to keep a new one
+func (c *Catalog) update(owner, dataName string, fn func(r *Record, exists bool) bool) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return c.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(recordsBucket)
+
+        record, exists := get(bucket, Record{Owner: owner, DataName: dataName}.id())
+        if !exists {
+            record = Record{Owner: owner, DataName: dataName}
+        }
+        if !fn(&record, exists) {
+            return nil
+        }
+        record.UpdatedAt = time.Now()
+        return put(bucket, record)
+    })
+}
+
+// Get returns the indexed record for owner and dataName
+func (c *Catalog) Get(owner, dataName string) (Record, bool, error) {
+    var record Record
+    var exists bool
+    err := c.db.View(func(tx *bolt.Tx) error {
+        record, exists = get(tx.Bucket(recordsBucket), Record{Owner: owner, DataName: dataName}.id())
+        return nil
+    })
+    return record, exists, err
+}
+
+// List returns the page of records matching q
+func (c *Catalog) List(q Query) (Page, error) {
+    if q.Sort == "" {
+        q.Sort = SortReleaseTime
+    }
+    if q.Limit <= 0 {
+        q.Limit = DefaultLimit
+    }
+    if q.Limit > MaxLimit {
+        q.Limit = MaxLimit
+    }
+    if _, err := sortKey(Record{}, q.Sort); err != nil {
+        return Page{}, err
+    }
+
+    var after string
+    if q.Cursor != "" {
+        raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+        if err != nil {
+            return Page{}, ErrInvalidCursor
+        }
+        field, key, found := strings.Cut(string(raw), ":")
+        if !found || field != q.Sort {
+            return Page{}, ErrInvalidCursor
+        }
+        after = key
+    }
+
+    var matches []Record
+    err := c.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(recordsBucket).ForEach(func(_, value []byte) error {
+            var record Record
+            if err := json.Unmarshal(value, &record); err != nil {
+                return err
+            }
+            if q.matches(record) {
+                matches = append(matches, record)
+            }
+            return nil
+        })
+    })
+    if err != nil {
+        return Page{}, err
+    }
+
+    keys := make(map[string]string, len(matches))
+    for _, record := range matches {
+        keys[record.id()], _ = sortKey(record, q.Sort)
+    }
+    sort.Slice(matches, func(i, j int) bool {
+        if q.Descending {
+            return keys[matches[i].id()] > keys[matches[j].id()]
+        }
+        return keys[matches[i].id()] < keys[matches[j].id()]
+    })
+
+    page := Page{Records: []Record{}, Total: len(matches)}
+    for _, record := range matches {
+        key := keys[record.id()]
+        if after != "" && ((!q.Descending && key <= after) || (q.Descending && key >= after)) {
+            continue
+        }
+        if len(page.Records) == q.Limit {
+            last := keys[page.Records[len(page.Records)-1].id()]
+            page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(q.Sort + ":" + last))
+            break
+        }
+        page.Records = append(page.Records, record)
+    }
+    return page, nil
+}
+
+func (q Query) matches(record Record) bool {
+    if q.Owner != "" && record.Owner != q.Owner {
+        return false
+    }
+    if q.Phase != nil && record.Phase != *q.Phase {
+        return false
+    }
+    if q.KeyReleased != nil && record.KeyReleased != *q.KeyReleased {
+        return false
+    }
+    if q.ReleaseFrom != 0 && record.ReleaseTime < q.ReleaseFrom {
+        return false
+    }
+    if q.ReleaseTo != 0 && record.ReleaseTime > q.ReleaseTo {
+        return false
+    }
+    return true
+}
+
+// sortKey orders records by field, breaking ties on owner and data name
+func sortKey(record Record, field string) (string, error) {
+    switch field {
+    case SortReleaseTime:
+        return fmt.Sprintf("%020d\x00%s", record.ReleaseTime, record.id()), nil
+    case SortOwner:
+        return record.id(), nil
+    case SortDataName:
+        return record.DataName + "\x00" + record.Owner, nil
+    }
+    return "", fmt.Errorf("%w %q", ErrInvalidSort, field)
+}
+
+// Status returns the outcome of the last full sync
+func (c *Catalog) Status() (SyncStatus, error) {
+    var status SyncStatus
+    err := c.db.View(func(tx *bolt.Tx) error {
+        value := tx.Bucket(metaBucket).Get(syncKey)
+        if value == nil {
+            return nil
+        }
+        return json.Unmarshal(value, &status)
+    })
+    return status, err
+}
+
+// Wait blocks until the sync loop has exited
+func (c *Catalog) Wait() {
+    c.wg.Wait()
+}
+
+// Close closes the record index
+func (c *Catalog) Close() error {
+    return c.db.Close()
+}
+
+func get(bucket *bolt.Bucket, id string) (Record, bool) {
+    value := bucket.Get([]byte(id))
+    if value == nil {
+        return Record{}, false
+    }
+    var record Record
+    if err := json.Unmarshal(value, &record); err != nil {
+        return Record{}, false
+    }
+    return record, true
+}
+
+func put(bucket *bolt.Bucket, record Record) error {
+    value, err := json.Marshal(record)
+    if err != nil {
+        return err
+    }
+    return bucket.Put([]byte(record.id()), value)
+}
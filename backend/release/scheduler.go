@@ -0,0 +1,606 @@
+package release
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "math/big"
+    "os"
+    "sync"
+    "time"
+
+    "web3server/bindings"
+    ks "web3server/keystore"
+    "web3server/signer"
+    "web3server/txmgr"
+
+    "github.com/ethereum/go-ethereum"
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/core/types"
+    bolt "go.etcd.io/bbolt"
+)
+
+// Status describes where a record is in the key release flow
+type Status string
+
+const (
+    StatusScheduled Status = "scheduled"
+    StatusSubmitted Status = "submitted"
+    StatusReleased  Status = "released"
+    StatusConfirmed Status = "confirmed"
+    StatusFailed    Status = "failed"
+)
+
+var releasesBucket = []byte("releases")
+
+// KeyLookup returns the release key stored for a record
+type KeyLookup func(owner, dataName string) ([]byte, error)
+
+// Backend is the part of a client needed to price, send and follow releaseKey
+type Backend interface {
+    bind.DeployBackend
+    txmgr.FeeBackend
+    NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+    TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error)
+}
+
+// SchedulerConfig holds everything the scheduler needs to send releaseKey
+type SchedulerConfig struct {
+    Client       Backend
+    Contract     *bindings.TwoPhaseCommit
+    Signer       signer.Signer
+    ChainID      *big.Int
+    GasStrategy  txmgr.GasStrategy
+    Nonces       *txmgr.NonceManager
+    Transactions *txmgr.Tracker
+    KeyLookup    KeyLookup
+    Path         string
+    PollInterval time.Duration
+    RetryDelay   time.Duration
+    MaxRetries   int
+    // MineTimeout is how long a sent releaseKey may stay unmined before the
+    // node is asked whether it still has it
+    MineTimeout time.Duration
+}
+
+// Record tracks the release state of a single uploaded record
+type Record struct {
+    Owner       string    `json:"owner"`
+    DataName    string    `json:"dataName"`
+    ReleaseTime uint64    `json:"releaseTime"`
+    Status      Status    `json:"status"`
+    Attempts    int       `json:"attempts"`
+    LastError   string    `json:"lastError,omitempty"`
+    TxHash      string    `json:"transactionHash,omitempty"`
+    Nonce       uint64    `json:"nonce,omitempty"`
+    SentAt      time.Time `json:"sentAt"`
+    NextAttempt time.Time `json:"nextAttempt"`
+    ReleasedAt  time.Time `json:"releasedAt"`
+    RolledBack  int       `json:"rolledBack"`
+}
+
+// Scheduler publishes release keys once the chain passes each record's
+// release time. Every change is written to disk so the schedule survives
+// restarts.
+type Scheduler struct {
+    Config  SchedulerConfig
+    db      *bolt.DB
+    records map[string]*Record
+    mu      sync.RWMutex
+    wg      sync.WaitGroup
+    logger  *log.Logger
+}
+
+// NewScheduler opens (or creates) the schedule at config.Path and loads the
+// records tracked by previous runs, filling in defaults for unset timings
+func NewScheduler(config SchedulerConfig) (*Scheduler, error) {
+    if config.Client == nil || config.Contract == nil {
+        return nil, errors.New("scheduler requires a client and contract")
+    }
+    if config.Signer == nil {
+        return nil, errors.New("scheduler requires a signer")
+    }
+    if config.Nonces == nil {
+        return nil, errors.New("scheduler requires a nonce manager")
+    }
+    if config.KeyLookup == nil {
+        return nil, errors.New("scheduler requires a key lookup")
+    }
+    if config.GasStrategy == nil {
+        config.GasStrategy = txmgr.Normal
+    }
+    if config.Path == "" {
+        config.Path = "releases.db"
+    }
+    if config.PollInterval == 0 {
+        config.PollInterval = 15 * time.Second
+    }
+    if config.RetryDelay == 0 {
+        config.RetryDelay = 10 * time.Second
+    }
+    if config.MaxRetries == 0 {
+        config.MaxRetries = 5
+    }
+    if config.MineTimeout == 0 {
+        config.MineTimeout = 2 * time.Minute
+    }
+
+    db, err := bolt.Open(config.Path, 0600, &bolt.Options{Timeout: time.Second})
+    if err != nil {
+        return nil, fmt.Errorf("failed to open release schedule %s: %w", config.Path, err)
+    }
+
+    records := make(map[string]*Record)
+    err = db.Update(func(tx *bolt.Tx) error {
+        bucket, err := tx.CreateBucketIfNotExists(releasesBucket)
+        if err != nil {
+            return err
+        }
+        // Schedules written before keys were length-prefixed are moved over
+        stale := make(map[string]*Record)
+        err = bucket.ForEach(func(key, value []byte) error {
+            var record Record
+            if err := json.Unmarshal(value, &record); err != nil {
+                return err
+            }
+            id := recordKey(record.Owner, record.DataName)
+            records[id] = &record
+            if string(key) != id {
+                stale[string(key)] = &record
+            }
+            return nil
+        })
+        if err != nil {
+            return err
+        }
+        for key, record := range stale {
+            if err := bucket.Delete([]byte(key)); err != nil {
+                return err
+            }
+            if err := putRecord(bucket, record); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to load release schedule: %w", err)
+    }
+
+    return &Scheduler{
+        Config:  config,
+        db:      db,
+        records: records,
+        logger:  log.New(os.Stdout, "[Release] ", log.LstdFlags|log.Lmicroseconds),
+    }, nil
+}
+
+// recordKey identifies a record both in memory and on disk
+func recordKey(owner, dataName string) string {
+    return string(ks.RecordKey(owner, dataName))
+}
+
+func putRecord(bucket *bolt.Bucket, record *Record) error {
+    value, err := json.Marshal(record)
+    if err != nil {
+        return err
+    }
+    return bucket.Put([]byte(recordKey(record.Owner, record.DataName)), value)
+}
+
+// Track schedules a record for key release at its release time. A record
+// that is already tracked keeps its state, so tracking it again never sends
+// a second releaseKey.
+func (s *Scheduler) Track(owner, dataName string, releaseTime uint64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    id := recordKey(owner, dataName)
+    if _, exists := s.records[id]; exists {
+        return nil
+    }
+    record := &Record{
+        Owner:       owner,
+        DataName:    dataName,
+        ReleaseTime: releaseTime,
+        Status:      StatusScheduled,
+    }
+    if err := s.save(record); err != nil {
+        return fmt.Errorf("failed to save release schedule: %w", err)
+    }
+    s.records[id] = record
+    return nil
+}
+
+// Get returns a copy of the release state for a record
+func (s *Scheduler) Get(owner, dataName string) (Record, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    record, exists := s.records[recordKey(owner, dataName)]
+    if !exists {
+        return Record{}, false
+    }
+    return *record, true
+}
+
+// Records returns a copy of the release state for every tracked record
+func (s *Scheduler) Records() []Record {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    records := make([]Record, 0, len(s.records))
+    for _, record := range s.records {
+        records = append(records, *record)
+    }
+    return records
+}
+
+// Confirm marks a record final once its KeyReleased event has enough
+// confirmations, whichever transaction ended up publishing the key.
+func (s *Scheduler) Confirm(owner, dataName, txHash string) {
+    s.update(owner, dataName, func(r *Record) {
+        r.Status = StatusConfirmed
+        r.LastError = ""
+        r.TxHash = txHash
+        if r.ReleasedAt.IsZero() {
+            r.ReleasedAt = time.Now()
+        }
+    })
+}
+
+// Rollback reschedules a record whose releaseKey transaction was removed by
+// a reorg. If the node re-mines the transaction, Confirm settles it again.
+func (s *Scheduler) Rollback(owner, dataName, txHash string) {
+    s.update(owner, dataName, func(r *Record) {
+        if r.TxHash != txHash || (r.Status != StatusReleased && r.Status != StatusConfirmed) {
+            return
+        }
+        s.logger.Printf("Release of %s/%s in %s was reorged out, rescheduling", owner, dataName, txHash)
+        r.Status = StatusScheduled
+        r.Attempts = 0
+        r.RolledBack++
+        r.LastError = fmt.Sprintf("transaction %s was removed by a reorg", txHash)
+        r.ReleasedAt = time.Time{}
+        r.NextAttempt = time.Now().Add(s.Config.RetryDelay)
+    })
+}
+
+// Start runs the release loop until the context is cancelled, first settling
+// releases a previous run left in flight
+func (s *Scheduler) Start(ctx context.Context) {
+    s.wg.Add(1)
+    go func() {
+        defer s.wg.Done()
+
+        s.resume(ctx)
+
+        ticker := time.NewTicker(s.Config.PollInterval)
+        defer ticker.Stop()
+
+        for {
+            select {
+            case <-ticker.C:
+                s.tick(ctx)
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+}
+
+// Wait blocks until the release loop has exited
+func (s *Scheduler) Wait() {
+    s.wg.Wait()
+}
+
+// Close releases the schedule database
+func (s *Scheduler) Close() error {
+    return s.db.Close()
+}
+
+// resume settles records that a previous run marked submitted before it
+// had sent anything. Whether their releaseKey went out is unknown, so the
+// chain decides: a released key waits for its KeyReleased event to confirm,
+// anything else is sent again. Records with a transaction are followed by
+// tick like any other.
+func (s *Scheduler) resume(ctx context.Context) {
+    s.mu.RLock()
+    var submitted []Record
+    for _, record := range s.records {
+        if record.Status == StatusSubmitted && record.TxHash == "" {
+            submitted = append(submitted, *record)
+        }
+    }
+    s.mu.RUnlock()
+
+    for _, record := range submitted {
+        _, _, _, _, _, released, err := s.Config.Contract.GetPublicData(&bind.CallOpts{Context: ctx}, record.DataName, record.Owner)
+        if err != nil {
+            s.logger.Printf("Failed to look up %s/%s, leaving it submitted: %v", record.Owner, record.DataName, err)
+            continue
+        }
+        s.update(record.Owner, record.DataName, func(r *Record) {
+            if r.Status != StatusSubmitted {
+                return
+            }
+            if released {
+                r.Status = StatusReleased
+                r.LastError = ""
+                if r.ReleasedAt.IsZero() {
+                    r.ReleasedAt = time.Now()
+                }
+                return
+            }
+            s.logger.Printf("Release of %s/%s was interrupted by a restart, rescheduling", r.Owner, r.DataName)
+            r.Status = StatusScheduled
+            r.NextAttempt = time.Time{}
+        })
+    }
+}
+
+// tick follows the releaseKey transactions already sent and sends one for
+// every record whose release time has passed on chain. Neither step waits
+// for a transaction to mine.
+func (s *Scheduler) tick(ctx context.Context) {
+    for _, record := range s.inFlight() {
+        s.settle(ctx, record)
+    }
+
+    header, err := s.Config.Client.HeaderByNumber(ctx, nil)
+    if err != nil {
+        s.logger.Printf("Failed to fetch latest header: %v", err)
+        return
+    }
+
+    for _, record := range s.due(header.Time, time.Now()) {
+        s.release(ctx, record)
+    }
+}
+
+// due returns the records that are ready for a release attempt
+func (s *Scheduler) due(blockTime uint64, now time.Time) []Record {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    var due []Record
+    for _, record := range s.records {
+        if record.Status != StatusScheduled {
+            continue
+        }
+        if record.ReleaseTime > blockTime || now.Before(record.NextAttempt) {
+            continue
+        }
+        due = append(due, *record)
+    }
+    return due
+}
+
+// inFlight returns the records whose releaseKey has been sent but not settled
+func (s *Scheduler) inFlight() []Record {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    var sent []Record
+    for _, record := range s.records {
+        if record.Status == StatusSubmitted && record.TxHash != "" {
+            sent = append(sent, *record)
+        }
+    }
+    return sent
+}
+
+func (s *Scheduler) update(owner, dataName string, fn func(*Record)) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if record, exists := s.records[recordKey(owner, dataName)]; exists {
+        fn(record)
+        s.persist(record)
+    }
+}
+
+// save writes a record to disk; callers hold s.mu
+func (s *Scheduler) save(record *Record) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        return putRecord(tx.Bucket(releasesBucket), record)
+    })
+}
+
+// persist saves a changed record. A failed write only costs the change on
+// restart, so it is logged rather than returned.
+func (s *Scheduler) persist(record *Record) {
+    if err := s.save(record); err != nil {
+        s.logger.Printf("Failed to save release state for %s/%s: %v", record.Owner, record.DataName, err)
+    }
+}
+
+// release sends releaseKey for a record; tick follows the transaction from
+// then on
+func (s *Scheduler) release(ctx context.Context, record Record) {
+    s.update(record.Owner, record.DataName, func(r *Record) {
+        r.Attempts++
+    })
+
+    tx, err := s.send(ctx, record)
+    if err != nil {
+        s.retry(record, err)
+        return
+    }
+
+    s.logger.Printf("Sent releaseKey for %s/%s in %s", record.Owner, record.DataName, tx.Hash().Hex())
+    s.update(record.Owner, record.DataName, func(r *Record) {
+        r.Status = StatusSubmitted
+        r.LastError = ""
+        r.TxHash = tx.Hash().Hex()
+        r.Nonce = tx.Nonce()
+        r.SentAt = time.Now()
+    })
+}
+
+// retry records a failed attempt, backing off exponentially until the
+// record runs out of attempts
+func (s *Scheduler) retry(record Record, err error) {
+    s.logger.Printf("Failed to release key for %s/%s: %v", record.Owner, record.DataName, err)
+    s.update(record.Owner, record.DataName, func(r *Record) {
+        r.LastError = err.Error()
+        if r.Attempts >= s.Config.MaxRetries {
+            r.Status = StatusFailed
+            return
+        }
+        r.Status = StatusScheduled
+        r.NextAttempt = time.Now().Add(s.Config.RetryDelay * time.Duration(1<<(r.Attempts-1)))
+    })
+}
+
+// released records the transaction that published a record's key
+func (s *Scheduler) released(record Record, txHash string) {
+    s.logger.Printf("Released key for %s/%s in %s", record.Owner, record.DataName, txHash)
+    s.update(record.Owner, record.DataName, func(r *Record) {
+        r.Status = StatusReleased
+        r.LastError = ""
+        r.TxHash = txHash
+        r.ReleasedAt = time.Now()
+    })
+}
+
+// settle checks once whether a sent releaseKey, or a replacement for it,
+// has mined
+func (s *Scheduler) settle(ctx context.Context, record Record) {
+    hash := common.HexToHash(record.TxHash)
+
+    var receipt *types.Receipt
+    var err error
+    if s.Config.Transactions != nil {
+        // Follow the nonce through any speed-up or cancellation
+        receipt, err = s.Config.Transactions.Receipt(ctx, hash)
+    } else {
+        receipt, err = s.Config.Client.TransactionReceipt(ctx, hash)
+    }
+    if errors.Is(err, ethereum.NotFound) {
+        s.checkUnmined(ctx, record)
+        return
+    }
+    if err != nil {
+        s.logger.Printf("Failed to get receipt for %s: %v", record.TxHash, err)
+        return
+    }
+
+    if receipt.Status == types.ReceiptStatusSuccessful && len(receipt.Logs) > 0 {
+        s.released(record, receipt.TxHash.Hex())
+        return
+    }
+    s.conclude(ctx, record, receipt)
+}
+
+// checkUnmined keeps waiting on a pending releaseKey and only sends again
+// once its nonce went to another transaction or the node has lost it
+func (s *Scheduler) checkUnmined(ctx context.Context, record Record) {
+    nonce, err := s.Config.Client.NonceAt(ctx, s.Config.Signer.Address(), nil)
+    if err != nil {
+        s.logger.Printf("Failed to retrieve account nonce: %v", err)
+        return
+    }
+    if nonce > record.Nonce {
+        // Mined by a transaction we no longer know the hash of
+        s.conclude(ctx, record, nil)
+        return
+    }
+    if time.Since(record.SentAt) < s.Config.MineTimeout {
+        return
+    }
+
+    if s.Config.Transactions != nil {
+        for _, p := range s.Config.Transactions.Pending() {
+            if p.Nonce == record.Nonce {
+                return
+            }
+        }
+    }
+    _, _, err = s.Config.Client.TransactionByHash(ctx, common.HexToHash(record.TxHash))
+    if !errors.Is(err, ethereum.NotFound) {
+        return
+    }
+    s.retry(record, fmt.Errorf("transaction %s was dropped after %s", record.TxHash, s.Config.MineTimeout))
+}
+
+// conclude decides what a mined releaseKey nonce did from the record's state
+// on chain. A revert, a cancellation and releaseKey for a record the
+// contract does not hold all mine without publishing the key.
+func (s *Scheduler) conclude(ctx context.Context, record Record, receipt *types.Receipt) {
+    _, _, _, _, releaseTime, released, err := s.Config.Contract.GetPublicData(&bind.CallOpts{Context: ctx}, record.DataName, record.Owner)
+    if err != nil {
+        s.logger.Printf("Failed to look up %s/%s: %v", record.Owner, record.DataName, err)
+        return
+    }
+
+    txHash := record.TxHash
+    if receipt != nil {
+        txHash = receipt.TxHash.Hex()
+    }
+    switch {
+    case released:
+        // Confirm settles the hash of the transaction that emitted KeyReleased
+        s.released(record, txHash)
+    case releaseTime.Sign() == 0:
+        s.logger.Printf("Record %s/%s does not exist on chain, giving up", record.Owner, record.DataName)
+        s.update(record.Owner, record.DataName, func(r *Record) {
+            r.Status = StatusFailed
+            r.TxHash = txHash
+            r.LastError = "record does not exist on chain"
+        })
+    case receipt != nil && receipt.Status != types.ReceiptStatusSuccessful:
+        s.retry(record, fmt.Errorf("releaseKey transaction %s reverted in block %d", txHash, receipt.BlockNumber.Uint64()))
+    case receipt != nil && receipt.TxHash != common.HexToHash(record.TxHash):
+        s.retry(record, fmt.Errorf("releaseKey transaction was replaced by %s", txHash))
+    default:
+        s.retry(record, fmt.Errorf("nonce %d was mined without publishing the key", record.Nonce))
+    }
+}
+
+// send packs, signs and sends releaseKey
+func (s *Scheduler) send(ctx context.Context, record Record) (*types.Transaction, error) {
+    key, err := s.Config.KeyLookup(record.Owner, record.DataName)
+    if err != nil {
+        return nil, fmt.Errorf("failed to look up release key: %v", err)
+    }
+
+    fees, err := s.Config.GasStrategy.Fees(ctx, s.Config.Client)
+    if err != nil {
+        return nil, fmt.Errorf("failed to price transaction: %v", err)
+    }
+
+    opts := signer.TransactOpts(ctx, s.Config.Signer, s.Config.ChainID)
+    fees.Apply(opts)
+
+    releaseKey := func(opts *bind.TransactOpts) (*types.Transaction, error) {
+        return s.Config.Contract.ReleaseKey(opts, record.DataName, record.Owner, key)
+    }
+
+    gasLimit, err := bindings.EstimateGas(opts, releaseKey)
+    if err != nil {
+        return nil, fmt.Errorf("failed to estimate gas limit: %v", err)
+    }
+    opts.GasLimit = uint64(float64(gasLimit) * 1.1)
+
+    nonce, err := s.Config.Nonces.Next(ctx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to reserve account nonce: %v", err)
+    }
+    opts.Nonce = new(big.Int).SetUint64(nonce)
+
+    signedTx, err := releaseKey(opts)
+    if err != nil {
+        s.Config.Nonces.Failed(ctx, nonce, err)
+        return nil, fmt.Errorf("failed to send transaction: %v", err)
+    }
+    s.Config.Nonces.Sent(nonce)
+    if s.Config.Transactions != nil {
+        s.Config.Transactions.Watch(signedTx, "releaseKey")
+    }
+    return signedTx, nil
+}
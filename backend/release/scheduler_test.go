@@ -0,0 +1,200 @@
+package release
+
+import (
+    "context"
+    "math/big"
+    "net"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "web3server/signer"
+    "web3server/simchain"
+    "web3server/txmgr"
+)
+
+// newTestChain starts a simulated chain with the contract deployed
+func newTestChain(t *testing.T) *simchain.Chain {
+    t.Helper()
+
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    port := listener.Addr().(*net.TCPAddr).Port
+    listener.Close()
+
+    chain, err := simchain.NewChain(simchain.ChainConfig{WSPort: port})
+    if err != nil {
+        t.Fatalf("failed to start simulated chain: %v", err)
+    }
+    t.Cleanup(func() { chain.Close() })
+    if _, err := chain.Deploy(context.Background(), filepath.Join("..", "TwoPhaseCommit.json")); err != nil {
+        t.Fatalf("failed to deploy contract: %v", err)
+    }
+    return chain
+}
+
+// newTestScheduler opens a scheduler on chain that sends from the deployer
+func newTestScheduler(t *testing.T, chain *simchain.Chain, path string, keys KeyLookup) *Scheduler {
+    t.Helper()
+
+    deployer, err := signer.NewHexSigner(simchain.DeployerKey)
+    if err != nil {
+        t.Fatal(err)
+    }
+    nonces, err := txmgr.NewNonceManager(context.Background(), chain.Client, deployer.Address())
+    if err != nil {
+        t.Fatal(err)
+    }
+    scheduler, err := NewScheduler(SchedulerConfig{
+        Client:    chain.Client,
+        Contract:  chain.Contract,
+        Signer:    deployer,
+        ChainID:   big.NewInt(simchain.ChainID),
+        Nonces:    nonces,
+        KeyLookup: keys,
+        Path:      path,
+    })
+    if err != nil {
+        t.Fatalf("NewScheduler: %v", err)
+    }
+    return scheduler
+}
+
+func noKeys(owner, dataName string) ([]byte, error) {
+    return []byte("release key"), nil
+}
+
+// addRecord stores a record from the keeper account, which the scheduler
+// does not send from, and mines it
+func addRecord(t *testing.T, chain *simchain.Chain, owner, dataName string, releaseTime uint64) {
+    t.Helper()
+
+    keeper, err := signer.NewHexSigner(simchain.KeeperKey)
+    if err != nil {
+        t.Fatal(err)
+    }
+    opts := signer.TransactOpts(context.Background(), keeper, big.NewInt(simchain.ChainID))
+    if _, err := chain.Contract.AddStoredData(opts, []byte("ciphertext"), owner, dataName,
+        new(big.Int).SetUint64(releaseTime), []byte("hash")); err != nil {
+        t.Fatalf("AddStoredData: %v", err)
+    }
+    chain.Mine()
+}
+
+func TestTrackKeepsRecordsWithSlashesApart(t *testing.T) {
+    chain := newTestChain(t)
+    path := filepath.Join(t.TempDir(), "releases.db")
+
+    scheduler := newTestScheduler(t, chain, path, noKeys)
+    for _, record := range []Record{{Owner: "a/b", DataName: "c", ReleaseTime: 100}, {Owner: "a", DataName: "b/c", ReleaseTime: 200}} {
+        if err := scheduler.Track(record.Owner, record.DataName, record.ReleaseTime); err != nil {
+            t.Fatalf("Track: %v", err)
+        }
+    }
+
+    check := func(scheduler *Scheduler) {
+        t.Helper()
+        if len(scheduler.Records()) != 2 {
+            t.Fatalf("got %d records, want 2", len(scheduler.Records()))
+        }
+        for _, want := range []Record{{Owner: "a/b", DataName: "c", ReleaseTime: 100}, {Owner: "a", DataName: "b/c", ReleaseTime: 200}} {
+            record, exists := scheduler.Get(want.Owner, want.DataName)
+            if !exists || record.ReleaseTime != want.ReleaseTime {
+                t.Errorf("%s/%s: got %+v (exists %v), want release time %d", want.Owner, want.DataName, record, exists, want.ReleaseTime)
+            }
+        }
+    }
+    check(scheduler)
+
+    // Both survive a restart
+    scheduler.Close()
+    reopened := newTestScheduler(t, chain, path, noKeys)
+    defer reopened.Close()
+    check(reopened)
+}
+
+func TestTrackIsIdempotent(t *testing.T) {
+    chain := newTestChain(t)
+    scheduler := newTestScheduler(t, chain, filepath.Join(t.TempDir(), "releases.db"), noKeys)
+    defer scheduler.Close()
+
+    if err := scheduler.Track("owner", "data", 100); err != nil {
+        t.Fatalf("Track: %v", err)
+    }
+    scheduler.Confirm("owner", "data", "0x01")
+
+    // A confirmation callback that runs again must not reschedule the release
+    if err := scheduler.Track("owner", "data", 100); err != nil {
+        t.Fatalf("Track: %v", err)
+    }
+    record, _ := scheduler.Get("owner", "data")
+    if record.Status != StatusConfirmed || record.TxHash != "0x01" {
+        t.Errorf("tracking again changed the record: %+v", record)
+    }
+}
+
+func TestReleaseWaitsForThePendingTransaction(t *testing.T) {
+    chain := newTestChain(t)
+    scheduler := newTestScheduler(t, chain, filepath.Join(t.TempDir(), "releases.db"), noKeys)
+    defer scheduler.Close()
+    scheduler.Config.MineTimeout = time.Millisecond
+    ctx := context.Background()
+
+    head, err := chain.Head(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    releaseTime := head.Time + 60
+    addRecord(t, chain, "owner", "data", releaseTime)
+    if err := scheduler.Track("owner", "data", releaseTime); err != nil {
+        t.Fatalf("Track: %v", err)
+    }
+    if err := chain.AdvanceTime(2 * time.Minute); err != nil {
+        t.Fatal(err)
+    }
+
+    scheduler.tick(ctx)
+    sent, _ := scheduler.Get("owner", "data")
+    if sent.Status != StatusSubmitted || sent.TxHash == "" {
+        t.Fatalf("releaseKey was not sent: %+v", sent)
+    }
+
+    // Past MineTimeout, a transaction the node still holds is not sent again
+    time.Sleep(5 * time.Millisecond)
+    scheduler.tick(ctx)
+    scheduler.tick(ctx)
+    waiting, _ := scheduler.Get("owner", "data")
+    if waiting.Status != StatusSubmitted || waiting.TxHash != sent.TxHash || waiting.Attempts != 1 {
+        t.Fatalf("pending releaseKey was replaced: %+v", waiting)
+    }
+
+    chain.Mine()
+    scheduler.tick(ctx)
+    released, _ := scheduler.Get("owner", "data")
+    if released.Status != StatusReleased || released.TxHash != sent.TxHash {
+        t.Errorf("got %+v, want released in %s", released, sent.TxHash)
+    }
+}
+
+func TestReleaseOfMissingRecordFails(t *testing.T) {
+    chain := newTestChain(t)
+    scheduler := newTestScheduler(t, chain, filepath.Join(t.TempDir(), "releases.db"), noKeys)
+    defer scheduler.Close()
+    ctx := context.Background()
+
+    // releaseKey succeeds without logs when the contract has no such record
+    if err := scheduler.Track("owner", "missing", 1); err != nil {
+        t.Fatalf("Track: %v", err)
+    }
+    scheduler.tick(ctx)
+    chain.Mine()
+    scheduler.tick(ctx)
+
+    record, _ := scheduler.Get("owner", "missing")
+    if record.Status != StatusFailed || !strings.Contains(record.LastError, "does not exist") || record.Attempts != 1 {
+        t.Errorf("got %+v, want failed once as missing on chain", record)
+    }
+}
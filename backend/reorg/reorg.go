@@ -0,0 +1,72 @@
+package reorg
+
+import (
+    "sync"
+    "time"
+)
+
+// maxRecent bounds how many individual reorgs are kept for the API
+const maxRecent = 100
+
+// Reorg is one observed removal of a block's logs from the canonical chain
+type Reorg struct {
+    BlockNumber uint64    `json:"blockNumber"`
+    Depth       uint64    `json:"depth"`
+    TxHash      string    `json:"transactionHash,omitempty"`
+    Event       string    `json:"event,omitempty"`
+    DetectedAt  time.Time `json:"detectedAt"`
+}
+
+// Stats summarizes the reorgs seen by one listener
+type Stats struct {
+    Count    int     `json:"count"`
+    MaxDepth uint64  `json:"maxDepth"`
+    Recent   []Reorg `json:"recent"`
+}
+
+// Tracker counts reorgs and remembers the most recent ones
+type Tracker struct {
+    mu    sync.Mutex
+    stats Stats
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+    return &Tracker{stats: Stats{Recent: []Reorg{}}}
+}
+
+// Depth is how many blocks deep a reorg at block reached when head was current
+func Depth(head, block uint64) uint64 {
+    if head < block {
+        return 1
+    }
+    return head - block + 1
+}
+
+// Record adds an observed reorg
+func (t *Tracker) Record(r Reorg) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    if r.DetectedAt.IsZero() {
+        r.DetectedAt = time.Now()
+    }
+    t.stats.Count++
+    if r.Depth > t.stats.MaxDepth {
+        t.stats.MaxDepth = r.Depth
+    }
+    t.stats.Recent = append(t.stats.Recent, r)
+    if len(t.stats.Recent) > maxRecent {
+        t.stats.Recent = t.stats.Recent[len(t.stats.Recent)-maxRecent:]
+    }
+}
+
+// Stats returns a copy of the counters
+func (t *Tracker) Stats() Stats {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    stats := t.stats
+    stats.Recent = append([]Reorg{}, t.stats.Recent...)
+    return stats
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+    "context"
+    "math/big"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+
+    h "web3server/helper"
+    "web3server/indexer"
+    "web3server/records"
+    r "web3server/release"
+    "web3server/signer"
+    "web3server/simchain"
+    "web3server/txmgr"
+    v "web3server/verify"
+
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/core/types"
+)
+
+// newTestChain starts a simulated chain on a free port with TwoPhaseCommit
+// deployed from the artifact next to this file
+func newTestChain(t *testing.T) *simchain.Chain {
+    t.Helper()
+
+    chain, err := simchain.NewChain(simchain.ChainConfig{WSPort: freePort(t)})
+    if err != nil {
+        t.Fatalf("failed to start simulated chain: %v", err)
+    }
+    t.Cleanup(func() { chain.Close() })
+
+    if _, err := chain.Deploy(context.Background(), "TwoPhaseCommit.json"); err != nil {
+        t.Fatalf("failed to deploy contract: %v", err)
+    }
+    return chain
+}
+
+// eventually polls cond until it holds or the test times out
+func eventually(t *testing.T, what string, cond func() bool) {
+    t.Helper()
+
+    deadline := time.Now().Add(15 * time.Second)
+    for !cond() {
+        if time.Now().After(deadline) {
+            t.Fatalf("timed out waiting for %s", what)
+        }
+        time.Sleep(20 * time.Millisecond)
+    }
+}
+
+// mineTx mines the block holding tx and fails the test if it reverted
+func mineTx(t *testing.T, chain *simchain.Chain, tx *types.Transaction, err error) *types.Receipt {
+    t.Helper()
+
+    if err != nil {
+        t.Fatalf("failed to send transaction: %v", err)
+    }
+    chain.Mine()
+    receipt, err := bind.WaitMined(context.Background(), chain.Client, tx)
+    if err != nil {
+        t.Fatalf("failed to get receipt: %v", err)
+    }
+    if receipt.Status != types.ReceiptStatusSuccessful {
+        t.Fatalf("transaction %s reverted", tx.Hash().Hex())
+    }
+    return receipt
+}
+
+// eventLog records what the indexer hands to the server's callbacks
+type eventLog struct {
+    mu        sync.Mutex
+    confirmed []indexer.Event
+    removed   []indexer.Event
+}
+
+func (l *eventLog) onConfirmed(event indexer.Event) {
+    l.mu.Lock()
+    l.confirmed = append(l.confirmed, event)
+    l.mu.Unlock()
+    onEventConfirmed(event)
+}
+
+func (l *eventLog) onRemoved(event indexer.Event) {
+    l.mu.Lock()
+    l.removed = append(l.removed, event)
+    l.mu.Unlock()
+    onEventRemoved(event)
+}
+
+func (l *eventLog) find(events *[]indexer.Event, name string, withKey bool) bool {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    for _, event := range *events {
+        if event.Name == name && (len(event.PrivateKey) > 0) == withKey {
+            return true
+        }
+    }
+    return false
+}
+
+// serverHarness wires the indexer, catalog, releaser and verifier to a
+// simulated chain through the same callbacks main uses
+type serverHarness struct {
+    chain    *simchain.Chain
+    deployer signer.Signer
+    nonces   *txmgr.NonceManager
+    index    *indexer.Indexer
+    log      *eventLog
+}
+
+func newServerHarness(t *testing.T, confirmations uint64, releaseKey []byte) *serverHarness {
+    t.Helper()
+    ctx, cancel := context.WithCancel(context.Background())
+    t.Cleanup(cancel)
+
+    chain := newTestChain(t)
+    dir := t.TempDir()
+
+    deployer, err := signer.NewHexSigner(simchain.DeployerKey)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    savedContract, savedCatalog, savedReleaser, savedVerifier := contract, catalog, releaser, verifier
+    t.Cleanup(func() {
+        contract, catalog, releaser, verifier = savedContract, savedCatalog, savedReleaser, savedVerifier
+    })
+    contract = chain.Contract
+
+    catalog, err = records.NewCatalog(records.CatalogConfig{
+        Client:   chain.Client,
+        Contract: chain.Contract,
+        Path:     filepath.Join(dir, "records.db"),
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { catalog.Close() })
+
+    nonces, err := txmgr.NewNonceManager(ctx, chain.Client, deployer.Address())
+    if err != nil {
+        t.Fatal(err)
+    }
+    releaser, err = r.NewScheduler(r.SchedulerConfig{
+        Client:       chain.Client,
+        Contract:     chain.Contract,
+        Signer:       deployer,
+        ChainID:      big.NewInt(simchain.ChainID),
+        Nonces:       nonces,
+        KeyLookup:    func(owner, dataName string) ([]byte, error) { return releaseKey, nil },
+        Path:         filepath.Join(dir, "releases.db"),
+        PollInterval: 50 * time.Millisecond,
+        RetryDelay:   100 * time.Millisecond,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { releaser.Close() })
+
+    verifier, err = v.NewVerifier(v.VerifierConfig{
+        Client:   chain.Client,
+        Contract: chain.Contract,
+        FetchRecord: func(ctx context.Context, dataName, owner string) (h.PublicData, error) {
+            return h.PublicData{}, nil
+        },
+        Path: filepath.Join(dir, "verifications.db"),
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { verifier.Close() })
+
+    harness := &serverHarness{chain: chain, deployer: deployer, nonces: nonces, log: &eventLog{}}
+    harness.index, err = indexer.NewIndexer(indexer.IndexerConfig{
+        Client:          chain.Client,
+        Contract:        chain.Contract,
+        ContractAddress: chain.Address,
+        Path:            filepath.Join(dir, "events.db"),
+        StartBlock:      chain.Deployed,
+        RetryDelay:      100 * time.Millisecond,
+        Confirmations:   confirmations,
+        ConfirmInterval: 50 * time.Millisecond,
+        OnConfirmed:     harness.log.onConfirmed,
+        OnRemoved:       harness.log.onRemoved,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { harness.index.Close() })
+    t.Cleanup(cancel)
+    harness.index.Start(ctx)
+    eventually(t, "the indexer to go live", func() bool { return harness.index.Status().Live })
+
+    return harness
+}
+
+func (s *serverHarness) opts() *bind.TransactOpts {
+    return signer.TransactOpts(context.Background(), s.deployer, big.NewInt(simchain.ChainID))
+}
+
+// addRecord stores a record whose release time is releaseIn after the head
+func (s *serverHarness) addRecord(t *testing.T, owner, dataName string, releaseIn time.Duration) uint64 {
+    t.Helper()
+
+    head, err := s.chain.Head(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    releaseTime := head.Time + uint64(releaseIn/time.Second)
+    tx, err := s.chain.Contract.AddStoredData(s.opts(), []byte("ciphertext"), owner, dataName,
+        new(big.Int).SetUint64(releaseTime), []byte("hash"))
+    mineTx(t, s.chain, tx, err)
+
+    if err := catalog.Refresh(context.Background(), owner, dataName); err != nil {
+        t.Fatalf("failed to index record: %v", err)
+    }
+    if err := releaser.Track(owner, dataName, releaseTime); err != nil {
+        t.Fatalf("failed to track record: %v", err)
+    }
+    return releaseTime
+}
+
+func TestEventsConfirmAfterConfirmations(t *testing.T) {
+    const confirmations = 4
+    key := []byte("release key")
+    s := newServerHarness(t, confirmations, key)
+
+    s.addRecord(t, "owner", "confirm", 24*time.Hour)
+    tx, err := s.chain.Contract.ReleaseKey(s.opts(), "confirm", "owner", key)
+    receipt := mineTx(t, s.chain, tx, err)
+
+    // Up to one block short of the threshold the event stays unconfirmed
+    for depth := uint64(1); depth < confirmations; depth++ {
+        time.Sleep(200 * time.Millisecond)
+        if s.log.find(&s.log.confirmed, indexer.EventKeyReleased, true) {
+            t.Fatalf("KeyReleased confirmed at depth %d, want %d", depth, confirmations)
+        }
+        if record, _ := releaser.Get("owner", "confirm"); record.Status == r.StatusConfirmed {
+            t.Fatalf("release confirmed at depth %d, want %d", depth, confirmations)
+        }
+        s.chain.Mine()
+    }
+
+    eventually(t, "KeyReleased to confirm", func() bool {
+        return s.log.find(&s.log.confirmed, indexer.EventKeyReleased, true)
+    })
+    head, err := s.chain.Client.BlockNumber(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if depth := head - receipt.BlockNumber.Uint64() + 1; depth != confirmations {
+        t.Errorf("confirmed at depth %d, want %d", depth, confirmations)
+    }
+
+    record, _ := releaser.Get("owner", "confirm")
+    if record.Status != r.StatusConfirmed || record.TxHash != tx.Hash().Hex() {
+        t.Errorf("release is %s in %s, want confirmed in %s", record.Status, record.TxHash, tx.Hash().Hex())
+    }
+    indexed, _, err := catalog.Get("owner", "confirm")
+    if err != nil || !indexed.KeyReleased {
+        t.Errorf("catalog did not apply the confirmed release: %+v, %v", indexed, err)
+    }
+}
+
+func TestReorgRollsBackRemovedEvents(t *testing.T) {
+    const confirmations = 10
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    s := newServerHarness(t, confirmations, []byte("release key"))
+
+    s.addRecord(t, "owner", "reorg", 12*time.Hour+time.Minute)
+    fork, err := s.chain.Head(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Pass the release time, then move the record to phase 1 with a single
+    // upkeep and let the releaser publish its key
+    if err := s.chain.AdvanceTime(12*time.Hour + 2*time.Minute); err != nil {
+        t.Fatal(err)
+    }
+    tx, err := s.chain.Contract.PerformUpkeep(s.opts(), nil)
+    mineTx(t, s.chain, tx, err)
+
+    // The test sent its own transactions from the releaser's account
+    if err := s.nonces.Sync(ctx); err != nil {
+        t.Fatal(err)
+    }
+    releaseCtx, stopReleaser := context.WithCancel(ctx)
+    releaser.Start(releaseCtx)
+    eventually(t, "the releaser to publish the key", func() bool {
+        if pending, err := s.chain.Client.PendingTransactionCount(ctx); err == nil && pending > 0 {
+            s.chain.Mine()
+        }
+        record, _ := releaser.Get("owner", "reorg")
+        return record.Status == r.StatusReleased
+    })
+    stopReleaser()
+    releaser.Wait()
+    released, _ := releaser.Get("owner", "reorg")
+
+    eventually(t, "both events to be indexed", func() bool {
+        indexed, err := s.index.Events(indexer.Filter{DataName: "reorg"})
+        return err == nil && len(indexed) == 2
+    })
+    if err := catalog.Sync(ctx); err != nil {
+        t.Fatalf("failed to sync catalog: %v", err)
+    }
+    if record, _, _ := catalog.Get("owner", "reorg"); record.Phase != 1 || !record.KeyReleased {
+        t.Fatalf("catalog before the reorg: phase %d, key released %t", record.Phase, record.KeyReleased)
+    }
+
+    // Replace everything after the record was added with empty blocks
+    if err := s.chain.Backend.Fork(fork.Hash()); err != nil {
+        t.Fatalf("failed to fork: %v", err)
+    }
+    for i := 0; i < confirmations+5; i++ {
+        s.chain.Mine()
+    }
+
+    eventually(t, "both events to be removed", func() bool {
+        return s.log.find(&s.log.removed, indexer.EventReleaseEncryptedData, false) &&
+            s.log.find(&s.log.removed, indexer.EventKeyReleased, true)
+    })
+    if s.log.find(&s.log.confirmed, indexer.EventReleaseEncryptedData, false) ||
+        s.log.find(&s.log.confirmed, indexer.EventKeyReleased, true) {
+        t.Errorf("removed events were confirmed")
+    }
+
+    record, _, err := catalog.Get("owner", "reorg")
+    if err != nil || record.Phase != 0 || record.KeyReleased {
+        t.Errorf("catalog after the reorg: phase %d, key released %t, %v", record.Phase, record.KeyReleased, err)
+    }
+
+    rolledBack, _ := releaser.Get("owner", "reorg")
+    if rolledBack.Status != r.StatusScheduled || rolledBack.RolledBack != 1 {
+        t.Errorf("release after the reorg is %s with %d rollbacks, want scheduled with 1", rolledBack.Status, rolledBack.RolledBack)
+    }
+    if rolledBack.LastError == "" || released.TxHash == "" {
+        t.Errorf("rollback did not record the removed transaction %s", released.TxHash)
+    }
+
+    stats := s.index.Reorgs()
+    if stats.Count != 2 || stats.MaxDepth < confirmations {
+        t.Errorf("reorg stats: %d reorgs, max depth %d; want 2 reorgs at least %d deep", stats.Count, stats.MaxDepth, confirmations)
+    }
+}
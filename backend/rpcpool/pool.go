@@ -0,0 +1,402 @@
+package rpcpool
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "math/big"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/ethereum/go-ethereum"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/core/types"
+    "github.com/ethereum/go-ethereum/ethclient"
+    "github.com/ethereum/go-ethereum/rpc"
+)
+
+var ErrNoEndpoints = errors.New("no RPC endpoint available")
+
+// errorSmoothing weights the latest call in an endpoint's error rate
+const errorSmoothing = 0.2
+
+// PoolConfig holds the endpoints and health thresholds for a pool
+type PoolConfig struct {
+    Endpoints      []string
+    HealthInterval time.Duration
+    Timeout        time.Duration
+    MaxHeadLag     uint64
+    MaxLatency     time.Duration
+    MaxErrorRate   float64
+}
+
+// EndpointStatus is the health of one endpoint as seen by the pool
+type EndpointStatus struct {
+    URL       string    `json:"url"`
+    Connected bool      `json:"connected"`
+    Healthy   bool      `json:"healthy"`
+    Head      uint64    `json:"head"`
+    HeadLag   uint64    `json:"headLag"`
+    LatencyMs int64     `json:"latencyMs"`
+    ErrorRate float64   `json:"errorRate"`
+    Requests  uint64    `json:"requests"`
+    Failures  uint64    `json:"failures"`
+    LastError string    `json:"lastError,omitempty"`
+    CheckedAt time.Time `json:"checkedAt"`
+}
+
+type endpoint struct {
+    url       string
+    client    *ethclient.Client
+    head      uint64
+    latency   time.Duration
+    errorRate float64
+    requests  uint64
+    failures  uint64
+    lastError string
+    healthy   bool
+    checkedAt time.Time
+}
+
+// Pool spreads RPC traffic over several endpoints for the same chain. Reads
+// go to the healthiest endpoint and move on to the next one if it fails;
+// transactions fail over only when an endpoint could not be reached.
+type Pool struct {
+    Config    PoolConfig
+    endpoints []*endpoint
+    mu        sync.RWMutex
+    logger    *log.Logger
+}
+
+// NewPool dials every endpoint. Endpoints that cannot be reached yet are
+// retried on each health check; at least one must connect.
+func NewPool(ctx context.Context, config PoolConfig) (*Pool, error) {
+    if len(config.Endpoints) == 0 {
+        return nil, errors.New("pool requires at least one endpoint")
+    }
+    if config.HealthInterval == 0 {
+        config.HealthInterval = 10 * time.Second
+    }
+    if config.Timeout == 0 {
+        config.Timeout = 5 * time.Second
+    }
+    if config.MaxHeadLag == 0 {
+        config.MaxHeadLag = 3
+    }
+    if config.MaxLatency == 0 {
+        config.MaxLatency = 2 * time.Second
+    }
+    if config.MaxErrorRate == 0 {
+        config.MaxErrorRate = 0.5
+    }
+
+    p := &Pool{
+        Config: config,
+        logger: log.New(os.Stdout, "[RPC] ", log.LstdFlags|log.Lmicroseconds),
+    }
+    for _, url := range config.Endpoints {
+        p.endpoints = append(p.endpoints, &endpoint{url: url})
+    }
+
+    p.CheckHealth(ctx)
+    for _, e := range p.endpoints {
+        if e.client != nil {
+            return p, nil
+        }
+    }
+    p.Close()
+    return nil, fmt.Errorf("failed to connect to any of %d endpoints", len(config.Endpoints))
+}
+
+// Start runs periodic health checks until ctx is cancelled
+func (p *Pool) Start(ctx context.Context) {
+    go func() {
+        ticker := time.NewTicker(p.Config.HealthInterval)
+        defer ticker.Stop()
+
+        for {
+            select {
+            case <-ticker.C:
+                p.CheckHealth(ctx)
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+}
+
+// CheckHealth reconnects dropped endpoints and re-ranks all of them by head
+// lag, latency and error rate.
+func (p *Pool) CheckHealth(ctx context.Context) {
+    var wg sync.WaitGroup
+    for _, e := range p.endpoints {
+        wg.Add(1)
+        go func(e *endpoint) {
+            defer wg.Done()
+            p.probe(ctx, e)
+        }(e)
+    }
+    wg.Wait()
+
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    var maxHead uint64
+    for _, e := range p.endpoints {
+        if e.client != nil && e.head > maxHead {
+            maxHead = e.head
+        }
+    }
+    for _, e := range p.endpoints {
+        wasHealthy := e.healthy
+        e.healthy = e.client != nil &&
+            e.lastError == "" &&
+            maxHead-e.head <= p.Config.MaxHeadLag &&
+            e.latency <= p.Config.MaxLatency &&
+            e.errorRate <= p.Config.MaxErrorRate
+        if wasHealthy != e.healthy {
+            p.logger.Printf("Endpoint %s is now %s", e.url, healthLabel(e.healthy))
+        }
+    }
+}
+
+// probe dials e if needed and measures its head and latency
+func (p *Pool) probe(ctx context.Context, e *endpoint) {
+    ctx, cancel := context.WithTimeout(ctx, p.Config.Timeout)
+    defer cancel()
+
+    p.mu.RLock()
+    client := e.client
+    p.mu.RUnlock()
+
+    if client == nil {
+        dialed, err := ethclient.DialContext(ctx, e.url)
+        if err != nil {
+            p.mu.Lock()
+            e.lastError = err.Error()
+            e.checkedAt = time.Now()
+            p.mu.Unlock()
+            return
+        }
+        client = dialed
+    }
+
+    start := time.Now()
+    head, err := client.BlockNumber(ctx)
+    latency := time.Since(start)
+
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    e.client = client
+    e.checkedAt = time.Now()
+    p.record(e, err)
+    if err != nil {
+        // An endpoint that answers the probe with an error is not usable either
+        e.lastError = err.Error()
+        return
+    }
+    e.head = head
+    e.latency = latency
+    e.lastError = ""
+}
+
+// record folds the outcome of a call into e's error rate. Callers hold p.mu.
+func (p *Pool) record(e *endpoint, err error) {
+    e.requests++
+    outcome := 0.0
+    if err != nil && !answered(err) {
+        e.failures++
+        e.lastError = err.Error()
+        outcome = 1
+    }
+    e.errorRate = (1-errorSmoothing)*e.errorRate + errorSmoothing*outcome
+}
+
+// ranked returns connected endpoints, healthy ones first, then by head and
+// latency
+func (p *Pool) ranked() []*endpoint {
+    p.mu.RLock()
+    defer p.mu.RUnlock()
+
+    var list []*endpoint
+    for _, e := range p.endpoints {
+        if e.client != nil {
+            list = append(list, e)
+        }
+    }
+    sort.SliceStable(list, func(i, j int) bool {
+        a, b := list[i], list[j]
+        if a.healthy != b.healthy {
+            return a.healthy
+        }
+        if a.head != b.head {
+            return a.head > b.head
+        }
+        return a.latency < b.latency
+    })
+    return list
+}
+
+// Status reports every endpoint's health
+func (p *Pool) Status() []EndpointStatus {
+    p.mu.RLock()
+    defer p.mu.RUnlock()
+
+    var maxHead uint64
+    for _, e := range p.endpoints {
+        if e.head > maxHead {
+            maxHead = e.head
+        }
+    }
+
+    statuses := make([]EndpointStatus, 0, len(p.endpoints))
+    for _, e := range p.endpoints {
+        statuses = append(statuses, EndpointStatus{
+            URL:       e.url,
+            Connected: e.client != nil,
+            Healthy:   e.healthy,
+            Head:      e.head,
+            HeadLag:   maxHead - e.head,
+            LatencyMs: e.latency.Milliseconds(),
+            ErrorRate: e.errorRate,
+            Requests:  e.requests,
+            Failures:  e.failures,
+            LastError: e.lastError,
+            CheckedAt: e.checkedAt,
+        })
+    }
+    return statuses
+}
+
+// Close closes every endpoint connection
+func (p *Pool) Close() {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    for _, e := range p.endpoints {
+        if e.client != nil {
+            e.client.Close()
+            e.client = nil
+        }
+    }
+}
+
+// read runs fn against the best endpoint, moving down the ranking while
+// endpoints fail to answer. Errors returned by a node that did answer,
+// such as reverts or missing receipts, are passed straight back.
+func read[T any](p *Pool, fn func(*ethclient.Client) (T, error)) (T, error) {
+    var zero T
+    lastErr := ErrNoEndpoints
+    for _, e := range p.ranked() {
+        result, err := fn(e.client)
+        p.mu.Lock()
+        p.record(e, err)
+        p.mu.Unlock()
+
+        if err == nil || answered(err) {
+            return result, err
+        }
+        lastErr = fmt.Errorf("%s: %w", e.url, err)
+    }
+    return zero, lastErr
+}
+
+// answered reports whether err came back from a node that handled the
+// request, as opposed to a transport failure that another endpoint may not
+// have.
+func answered(err error) bool {
+    var rpcErr rpc.Error
+    return errors.Is(err, ethereum.NotFound) || errors.As(err, &rpcErr) || errors.Is(err, context.Canceled)
+}
+
+func healthLabel(healthy bool) string {
+    if healthy {
+        return "healthy"
+    }
+    return "unhealthy"
+}
+
+func (p *Pool) BlockNumber(ctx context.Context) (uint64, error) {
+    return read(p, func(c *ethclient.Client) (uint64, error) { return c.BlockNumber(ctx) })
+}
+
+func (p *Pool) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+    return read(p, func(c *ethclient.Client) (*types.Header, error) { return c.HeaderByNumber(ctx, number) })
+}
+
+func (p *Pool) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+    return read(p, func(c *ethclient.Client) ([]byte, error) { return c.CodeAt(ctx, account, blockNumber) })
+}
+
+func (p *Pool) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+    return read(p, func(c *ethclient.Client) ([]byte, error) { return c.PendingCodeAt(ctx, account) })
+}
+
+func (p *Pool) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+    return read(p, func(c *ethclient.Client) ([]byte, error) { return c.CallContract(ctx, call, blockNumber) })
+}
+
+func (p *Pool) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+    return read(p, func(c *ethclient.Client) (uint64, error) { return c.EstimateGas(ctx, call) })
+}
+
+func (p *Pool) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+    return read(p, func(c *ethclient.Client) (*big.Int, error) { return c.SuggestGasPrice(ctx) })
+}
+
+func (p *Pool) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+    return read(p, func(c *ethclient.Client) (*big.Int, error) { return c.SuggestGasTipCap(ctx) })
+}
+
+func (p *Pool) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+    return read(p, func(c *ethclient.Client) (uint64, error) { return c.NonceAt(ctx, account, blockNumber) })
+}
+
+func (p *Pool) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+    return read(p, func(c *ethclient.Client) (uint64, error) { return c.PendingNonceAt(ctx, account) })
+}
+
+func (p *Pool) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+    return read(p, func(c *ethclient.Client) (*types.Receipt, error) { return c.TransactionReceipt(ctx, txHash) })
+}
+
+func (p *Pool) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+    type result struct {
+        tx      *types.Transaction
+        pending bool
+    }
+    r, err := read(p, func(c *ethclient.Client) (result, error) {
+        tx, pending, err := c.TransactionByHash(ctx, txHash)
+        return result{tx, pending}, err
+    })
+    return r.tx, r.pending, err
+}
+
+func (p *Pool) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+    return read(p, func(c *ethclient.Client) ([]types.Log, error) { return c.FilterLogs(ctx, query) })
+}
+
+// SubscribeFilterLogs subscribes on the best endpoint. If that endpoint
+// drops, the subscription errors and the caller's resubscribe lands on
+// whichever endpoint is best by then.
+func (p *Pool) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+    return read(p, func(c *ethclient.Client) (ethereum.Subscription, error) { return c.SubscribeFilterLogs(ctx, query, ch) })
+}
+
+// SendTransaction submits tx to the best endpoint and fails over to the
+// next one only if the endpoint could not be reached. An endpoint that
+// already has the transaction from an earlier attempt counts as success.
+func (p *Pool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+    _, err := read(p, func(c *ethclient.Client) (struct{}, error) {
+        err := c.SendTransaction(ctx, tx)
+        if err != nil && strings.Contains(strings.ToLower(err.Error()), "already known") {
+            return struct{}{}, nil
+        }
+        return struct{}{}, err
+    })
+    return err
+}
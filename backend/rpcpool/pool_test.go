@@ -0,0 +1,237 @@
+package rpcpool
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "math/big"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/common/hexutil"
+    "github.com/ethereum/go-ethereum/core/types"
+    "github.com/ethereum/go-ethereum/crypto"
+    "github.com/ethereum/go-ethereum/rpc"
+)
+
+// fakeNode is a JSON-RPC endpoint that answers eth_blockNumber and
+// eth_sendRawTransaction and can be made slow, unreachable or failing
+type fakeNode struct {
+    mu       sync.Mutex
+    head     uint64
+    delay    time.Duration
+    down     bool
+    rpcError string
+    calls    map[string]int
+}
+
+func newFakeNode(t *testing.T, head uint64) (*fakeNode, string) {
+    node := &fakeNode{head: head, calls: make(map[string]int)}
+    server := httptest.NewServer(node)
+    t.Cleanup(server.Close)
+    return node, server.URL
+}
+
+func (n *fakeNode) set(fn func(n *fakeNode)) {
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    fn(n)
+}
+
+func (n *fakeNode) count(method string) int {
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    return n.calls[method]
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    var req struct {
+        ID     json.RawMessage `json:"id"`
+        Method string          `json:"method"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    n.mu.Lock()
+    n.calls[req.Method]++
+    head, delay, down, rpcError := n.head, n.delay, n.down, n.rpcError
+    n.mu.Unlock()
+
+    time.Sleep(delay)
+    if down {
+        http.Error(w, "node unavailable", http.StatusServiceUnavailable)
+        return
+    }
+
+    resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+    switch {
+    case rpcError != "":
+        resp["error"] = map[string]interface{}{"code": -32000, "message": rpcError}
+    case req.Method == "eth_blockNumber":
+        resp["result"] = hexutil.Uint64(head)
+    case req.Method == "eth_sendRawTransaction":
+        resp["result"] = common.Hash{}
+    default:
+        resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+    }
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(resp)
+}
+
+func newTestPool(t *testing.T, config PoolConfig) *Pool {
+    if config.Timeout == 0 {
+        config.Timeout = time.Second
+    }
+    pool, err := NewPool(context.Background(), config)
+    if err != nil {
+        t.Fatalf("NewPool: %v", err)
+    }
+    t.Cleanup(pool.Close)
+    return pool
+}
+
+func healthByURL(pool *Pool) map[string]EndpointStatus {
+    statuses := make(map[string]EndpointStatus)
+    for _, status := range pool.Status() {
+        statuses[status.URL] = status
+    }
+    return statuses
+}
+
+func signedTx(t *testing.T) *types.Transaction {
+    key, err := crypto.GenerateKey()
+    if err != nil {
+        t.Fatal(err)
+    }
+    tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1337)), &types.DynamicFeeTx{
+        ChainID:   big.NewInt(1337),
+        Gas:       21000,
+        GasTipCap: big.NewInt(1),
+        GasFeeCap: big.NewInt(2),
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tx
+}
+
+func TestCheckHealthMarksBadEndpointsUnhealthy(t *testing.T) {
+    _, good := newFakeNode(t, 100)
+    _, lagging := newFakeNode(t, 90)
+    slowNode, slow := newFakeNode(t, 100)
+    slowNode.set(func(n *fakeNode) { n.delay = 200 * time.Millisecond })
+    failingNode, failing := newFakeNode(t, 100)
+    failingNode.set(func(n *fakeNode) { n.rpcError = "internal error" })
+
+    pool := newTestPool(t, PoolConfig{
+        Endpoints:  []string{good, lagging, slow, failing},
+        MaxHeadLag: 3,
+        MaxLatency: 100 * time.Millisecond,
+    })
+
+    health := healthByURL(pool)
+    if !health[good].Healthy {
+        t.Errorf("up to date endpoint is unhealthy: %+v", health[good])
+    }
+    if health[lagging].Healthy || health[lagging].HeadLag != 10 {
+        t.Errorf("endpoint 10 blocks behind should be unhealthy: %+v", health[lagging])
+    }
+    if health[slow].Healthy {
+        t.Errorf("endpoint over the latency limit should be unhealthy: %+v", health[slow])
+    }
+    if health[failing].Healthy || health[failing].LastError == "" {
+        t.Errorf("erroring endpoint should be unhealthy: %+v", health[failing])
+    }
+
+    // A lagging endpoint that catches up recovers on the next check
+    slowNode.set(func(n *fakeNode) { n.delay = 0 })
+    pool.CheckHealth(context.Background())
+    if !healthByURL(pool)[slow].Healthy {
+        t.Errorf("endpoint should be healthy once it speeds up")
+    }
+}
+
+func TestReadFailsOverOnTransportError(t *testing.T) {
+    first, firstURL := newFakeNode(t, 101)
+    second, secondURL := newFakeNode(t, 100)
+    pool := newTestPool(t, PoolConfig{Endpoints: []string{firstURL, secondURL}})
+
+    first.set(func(n *fakeNode) { n.down = true })
+    head, err := pool.BlockNumber(context.Background())
+    if err != nil {
+        t.Fatalf("BlockNumber: %v", err)
+    }
+    if head != 100 {
+        t.Errorf("got head %d, want 100 from the second endpoint", head)
+    }
+    if first.count("eth_blockNumber") < 2 || second.count("eth_blockNumber") < 2 {
+        t.Errorf("expected the read to try both endpoints")
+    }
+}
+
+func TestReadDoesNotFailOverOnRPCError(t *testing.T) {
+    first, firstURL := newFakeNode(t, 101)
+    second, secondURL := newFakeNode(t, 100)
+    pool := newTestPool(t, PoolConfig{Endpoints: []string{firstURL, secondURL}})
+    before := second.count("eth_blockNumber")
+
+    first.set(func(n *fakeNode) { n.rpcError = "execution reverted" })
+    _, err := pool.BlockNumber(context.Background())
+    var rpcErr rpc.Error
+    if !errors.As(err, &rpcErr) {
+        t.Fatalf("got %v, want the node's rpc.Error", err)
+    }
+    if second.count("eth_blockNumber") != before {
+        t.Errorf("read failed over although the first endpoint answered")
+    }
+}
+
+func TestSendTransaction(t *testing.T) {
+    t.Run("already known is success", func(t *testing.T) {
+        first, firstURL := newFakeNode(t, 101)
+        second, secondURL := newFakeNode(t, 100)
+        pool := newTestPool(t, PoolConfig{Endpoints: []string{firstURL, secondURL}})
+
+        first.set(func(n *fakeNode) { n.rpcError = "already known" })
+        if err := pool.SendTransaction(context.Background(), signedTx(t)); err != nil {
+            t.Fatalf("SendTransaction: %v", err)
+        }
+        if second.count("eth_sendRawTransaction") != 0 {
+            t.Errorf("transaction was sent again to the second endpoint")
+        }
+    })
+
+    t.Run("rejection is returned", func(t *testing.T) {
+        first, firstURL := newFakeNode(t, 101)
+        second, secondURL := newFakeNode(t, 100)
+        pool := newTestPool(t, PoolConfig{Endpoints: []string{firstURL, secondURL}})
+
+        first.set(func(n *fakeNode) { n.rpcError = "nonce too low" })
+        if err := pool.SendTransaction(context.Background(), signedTx(t)); err == nil {
+            t.Fatalf("expected the rejection to be returned")
+        }
+        if second.count("eth_sendRawTransaction") != 0 {
+            t.Errorf("rejected transaction was sent to the second endpoint")
+        }
+    })
+
+    t.Run("unreachable endpoint fails over", func(t *testing.T) {
+        first, firstURL := newFakeNode(t, 101)
+        second, secondURL := newFakeNode(t, 100)
+        pool := newTestPool(t, PoolConfig{Endpoints: []string{firstURL, secondURL}})
+
+        first.set(func(n *fakeNode) { n.down = true })
+        if err := pool.SendTransaction(context.Background(), signedTx(t)); err != nil {
+            t.Fatalf("SendTransaction: %v", err)
+        }
+        if second.count("eth_sendRawTransaction") != 1 {
+            t.Errorf("transaction did not reach the second endpoint")
+        }
+    })
+}
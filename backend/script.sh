@@ -43,10 +43,16 @@ test_gas_usage() {
             
             # Extract relevant information
             tx_hash=$(echo $response | jq -r '.transactionHash')
-            block_number=$(echo $response | jq -r '.blockNumber')
+            job_id=$(echo $response | jq -r '.jobId')
             
-            # Get transaction details from blockchain
-            gas_used=$(cast tx $tx_hash | grep "gas used" | awk '{print $3}')
+            # Uploads return immediately, so poll the job until it settles
+            job=$(curl -s "$API_ENDPOINT/jobs/$job_id")
+            while [[ $(echo $job | jq -r '.status') =~ ^(pending|mined)$ ]]; do
+                sleep 2
+                job=$(curl -s "$API_ENDPOINT/jobs/$job_id")
+            done
+            block_number=$(echo $job | jq -r '.blockNumber')
+            gas_used=$(echo $job | jq -r '.gasUsed')
             
             # Record results
             echo "$size,$gas_used,$tx_hash,$block_number,$(date +%s)" >> "$OUTPUT_DIR/gas/gas_analysis.csv"
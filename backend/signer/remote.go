@@ -0,0 +1,192 @@
+package signer
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "math/big"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/common/hexutil"
+    "github.com/ethereum/go-ethereum/core/types"
+)
+
+// The remote signing protocol is two JSON endpoints:
+//
+//   GET  /address -> {"address": "0x..."}
+//   POST /sign    {"chainId": "0x..", "tx": "0x<binary tx>"} -> {"signedTx": "0x<binary tx>"}
+//
+// Errors are returned as {"error": "..."} with a non-200 status.
+
+type addressResponse struct {
+    Address common.Address `json:"address"`
+}
+
+type signRequest struct {
+    ChainID *hexutil.Big  `json:"chainId"`
+    Tx      hexutil.Bytes `json:"tx"`
+}
+
+type signResponse struct {
+    SignedTx hexutil.Bytes `json:"signedTx"`
+}
+
+type errorResponse struct {
+    Error string `json:"error"`
+}
+
+// RemoteSigner delegates signing to a service speaking the protocol above
+type RemoteSigner struct {
+    url        string
+    address    common.Address
+    httpClient *http.Client
+}
+
+// NewRemoteSigner connects to the signing service at url and asks it which
+// account it signs for
+func NewRemoteSigner(ctx context.Context, url string) (*RemoteSigner, error) {
+    rs := &RemoteSigner{
+        url:        strings.TrimSuffix(url, "/"),
+        httpClient: &http.Client{Timeout: 30 * time.Second},
+    }
+
+    var resp addressResponse
+    if err := rs.call(ctx, http.MethodGet, "/address", nil, &resp); err != nil {
+        return nil, fmt.Errorf("failed to fetch signer address: %w", err)
+    }
+    rs.address = resp.Address
+    return rs, nil
+}
+
+func (rs *RemoteSigner) Address() common.Address {
+    return rs.address
+}
+
+func (rs *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+    unsigned, err := tx.MarshalBinary()
+    if err != nil {
+        return nil, err
+    }
+
+    var resp signResponse
+    err = rs.call(ctx, http.MethodPost, "/sign", signRequest{ChainID: (*hexutil.Big)(chainID), Tx: unsigned}, &resp)
+    if err != nil {
+        return nil, fmt.Errorf("remote signer failed: %w", err)
+    }
+
+    signed := new(types.Transaction)
+    if err := signed.UnmarshalBinary(resp.SignedTx); err != nil {
+        return nil, fmt.Errorf("remote signer returned an invalid transaction: %w", err)
+    }
+    // Do not trust the service to have signed what we asked for
+    if !sameTransaction(signed, tx) {
+        return nil, fmt.Errorf("remote signer returned a different transaction")
+    }
+    if err := checkSender(signed, chainID, rs.address); err != nil {
+        return nil, err
+    }
+    return signed, nil
+}
+
+func sameTransaction(a, b *types.Transaction) bool {
+    if a.Type() != b.Type() || a.Nonce() != b.Nonce() || a.Gas() != b.Gas() {
+        return false
+    }
+    if (a.To() == nil) != (b.To() == nil) || (a.To() != nil && *a.To() != *b.To()) {
+        return false
+    }
+    return a.Value().Cmp(b.Value()) == 0 &&
+        a.GasFeeCap().Cmp(b.GasFeeCap()) == 0 &&
+        a.GasTipCap().Cmp(b.GasTipCap()) == 0 &&
+        bytes.Equal(a.Data(), b.Data())
+}
+
+func (rs *RemoteSigner) call(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+    var reader *bytes.Reader
+    if body != nil {
+        encoded, err := json.Marshal(body)
+        if err != nil {
+            return err
+        }
+        reader = bytes.NewReader(encoded)
+    } else {
+        reader = bytes.NewReader(nil)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, method, rs.url+path, reader)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := rs.httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        var apiErr errorResponse
+        json.NewDecoder(resp.Body).Decode(&apiErr)
+        return fmt.Errorf("signer returned %d: %s", resp.StatusCode, apiErr.Error)
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// NewRemoteServer exposes a Signer over the remote signing protocol. It is
+// meant as a localhost stand-in for a real signing service.
+func NewRemoteServer(s Signer) http.Handler {
+    mux := http.NewServeMux()
+
+    mux.HandleFunc("/address", func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet {
+            writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+            return
+        }
+        writeJSON(w, http.StatusOK, addressResponse{Address: s.Address()})
+    })
+
+    mux.HandleFunc("/sign", func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+            writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+            return
+        }
+
+        var req signRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChainID == nil {
+            writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid sign request"})
+            return
+        }
+
+        tx := new(types.Transaction)
+        if err := tx.UnmarshalBinary(req.Tx); err != nil {
+            writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid transaction: %v", err)})
+            return
+        }
+
+        signed, err := s.SignTx(r.Context(), tx, req.ChainID.ToInt())
+        if err != nil {
+            writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+            return
+        }
+
+        encoded, err := signed.MarshalBinary()
+        if err != nil {
+            writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+            return
+        }
+        writeJSON(w, http.StatusOK, signResponse{SignedTx: encoded})
+    })
+
+    return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,94 @@
+package signer
+
+import (
+    "context"
+    "crypto/ecdsa"
+    "errors"
+    "fmt"
+    "math/big"
+    "os"
+    "strings"
+
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/accounts/keystore"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/core/types"
+    "github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer signs transactions for the backend's sending account without
+// exposing how or where the key is held.
+type Signer interface {
+    Address() common.Address
+    SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// KeySigner signs with an in-memory ECDSA key
+type KeySigner struct {
+    key     *ecdsa.PrivateKey
+    address common.Address
+}
+
+// NewHexSigner parses a hex encoded private key, with or without 0x
+func NewHexSigner(hexKey string) (*KeySigner, error) {
+    key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse private key: %w", err)
+    }
+    return newKeySigner(key), nil
+}
+
+// NewKeystoreSigner decrypts a go-ethereum encrypted keystore JSON file
+func NewKeystoreSigner(path, passphrase string) (*KeySigner, error) {
+    keyJSON, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read keystore file: %w", err)
+    }
+
+    key, err := keystore.DecryptKey(keyJSON, passphrase)
+    if err != nil {
+        return nil, fmt.Errorf("failed to decrypt keystore file: %w", err)
+    }
+    return newKeySigner(key.PrivateKey), nil
+}
+
+func newKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+    return &KeySigner{
+        key:     key,
+        address: crypto.PubkeyToAddress(key.PublicKey),
+    }
+}
+
+func (ks *KeySigner) Address() common.Address {
+    return ks.address
+}
+
+func (ks *KeySigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+    return types.SignTx(tx, types.LatestSignerForChainID(chainID), ks.key)
+}
+
+// TransactOpts adapts a Signer for go-ethereum's bind package
+func TransactOpts(ctx context.Context, s Signer, chainID *big.Int) *bind.TransactOpts {
+    return &bind.TransactOpts{
+        From:    s.Address(),
+        Context: ctx,
+        Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+            if address != s.Address() {
+                return nil, bind.ErrNotAuthorized
+            }
+            return s.SignTx(ctx, tx, chainID)
+        },
+    }
+}
+
+// checkSender makes sure a signed transaction came from the expected account
+func checkSender(tx *types.Transaction, chainID *big.Int, expected common.Address) error {
+    sender, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+    if err != nil {
+        return fmt.Errorf("failed to recover sender: %w", err)
+    }
+    if sender != expected {
+        return errors.New("transaction was signed by an unexpected account")
+    }
+    return nil
+}
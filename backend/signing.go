@@ -0,0 +1,80 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "log"
+    "net/http"
+    "os"
+    "strings"
+
+    "web3server/signer"
+
+    "github.com/joho/godotenv"
+)
+
+// newSignerFromEnv builds the transaction signer selected by SIGNER_TYPE:
+//
+//   hex      PRIVATE_KEY holds the raw hex key (default)
+//   keystore SIGNER_KEYSTORE points at an encrypted keystore JSON file,
+//            unlocked with SIGNER_PASSPHRASE or SIGNER_PASSPHRASE_FILE
+//   remote   SIGNER_URL points at a remote signing service
+//
+// Secrets are removed from the environment once the signer holds them.
+func newSignerFromEnv(ctx context.Context) (signer.Signer, error) {
+    switch signerType := GetEnvDefault("SIGNER_TYPE", "hex"); signerType {
+    case "hex":
+        s, err := signer.NewHexSigner(MustGetEnv("PRIVATE_KEY"))
+        os.Unsetenv("PRIVATE_KEY")
+        return s, err
+
+    case "keystore":
+        passphrase, err := signerPassphrase()
+        if err != nil {
+            return nil, err
+        }
+        return signer.NewKeystoreSigner(MustGetEnv("SIGNER_KEYSTORE"), passphrase)
+
+    case "remote":
+        return signer.NewRemoteSigner(ctx, MustGetEnv("SIGNER_URL"))
+
+    default:
+        return nil, fmt.Errorf("unknown SIGNER_TYPE %q", signerType)
+    }
+}
+
+func signerPassphrase() (string, error) {
+    defer os.Unsetenv("SIGNER_PASSPHRASE")
+
+    if path := os.Getenv("SIGNER_PASSPHRASE_FILE"); path != "" {
+        passphrase, err := os.ReadFile(path)
+        if err != nil {
+            return "", fmt.Errorf("failed to read passphrase file: %v", err)
+        }
+        return strings.TrimRight(string(passphrase), "\r\n"), nil
+    }
+    return os.Getenv("SIGNER_PASSPHRASE"), nil
+}
+
+// runSignerServer is the "signer-server" command: a localhost stand-in for
+// a remote signing service, backed by a hex or keystore signer.
+func runSignerServer(args []string) {
+    fs := flag.NewFlagSet("signer-server", flag.ExitOnError)
+    listen := fs.String("listen", "127.0.0.1:8550", "address to serve the signing protocol on")
+    fs.Parse(args)
+
+    // The key may come from the environment directly, so .env is optional
+    godotenv.Load()
+    if GetEnvDefault("SIGNER_TYPE", "hex") == "remote" {
+        log.Fatalf("signer-server cannot itself use a remote signer")
+    }
+
+    s, err := newSignerFromEnv(context.Background())
+    if err != nil {
+        log.Fatalf("Failed to set up signer: %v", err)
+    }
+
+    log.Printf("Signing for %s on http://%s", s.Address().Hex(), *listen)
+    log.Fatal(http.ListenAndServe(*listen, signer.NewRemoteServer(s)))
+}
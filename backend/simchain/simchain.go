@@ -0,0 +1,292 @@
+package simchain
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "math/big"
+    "net/http"
+    "os"
+    "strconv"
+    "sync"
+    "time"
+
+    "web3server/bindings"
+    "web3server/signer"
+
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/core/types"
+    "github.com/ethereum/go-ethereum/eth/ethconfig"
+    "github.com/ethereum/go-ethereum/ethclient/simulated"
+    "github.com/ethereum/go-ethereum/node"
+    "github.com/ethereum/go-ethereum/params"
+)
+
+// ChainID is the chain ID go-ethereum's simulated backend always uses
+const ChainID = 1337
+
+// Well-known development keys (Hardhat accounts #0 and #1). They only ever
+// hold funds on local chains. Deploying from #0 at nonce 0 gives the same
+// contract address on every run.
+const (
+    DeployerKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+    KeeperKey   = "59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d"
+)
+
+// ChainConfig holds the settings for a simulated chain
+type ChainConfig struct {
+    WSHost    string
+    WSPort    int
+    Fund      []common.Address
+    Balance   *big.Int
+    BlockTime time.Duration
+    // Keeper stands in for Chainlink Automation, calling performUpkeep
+    // whenever checkUpkeep asks for it
+    Keeper bool
+}
+
+// Chain is an in-process go-ethereum chain served over a local WebSocket,
+// so the backend can reach it through its normal ethclient.Dial path.
+type Chain struct {
+    Config   ChainConfig
+    Backend  *simulated.Backend
+    Client   simulated.Client
+    Contract *bindings.TwoPhaseCommit
+    Address  common.Address
+    Deployed uint64
+
+    deployer *signer.KeySigner
+    keeper   *signer.KeySigner
+    chainID  *big.Int
+    mu       sync.Mutex
+    logger   *log.Logger
+}
+
+// NewChain starts a simulated chain with the deployer, keeper and every
+// configured account funded
+func NewChain(config ChainConfig) (*Chain, error) {
+    if config.WSHost == "" {
+        config.WSHost = "127.0.0.1"
+    }
+    if config.WSPort == 0 {
+        config.WSPort = 8546
+    }
+    if config.Balance == nil {
+        config.Balance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+    }
+
+    deployer, err := signer.NewHexSigner(DeployerKey)
+    if err != nil {
+        return nil, err
+    }
+    keeper, err := signer.NewHexSigner(KeeperKey)
+    if err != nil {
+        return nil, err
+    }
+
+    alloc := types.GenesisAlloc{
+        deployer.Address(): {Balance: config.Balance},
+        keeper.Address():   {Balance: config.Balance},
+    }
+    for _, account := range config.Fund {
+        alloc[account] = types.Account{Balance: config.Balance}
+    }
+
+    backend := simulated.NewBackend(alloc, func(nodeConf *node.Config, _ *ethconfig.Config) {
+        nodeConf.WSHost = config.WSHost
+        nodeConf.WSPort = config.WSPort
+        nodeConf.WSModules = []string{"eth", "net", "web3"}
+        nodeConf.WSOrigins = []string{"*"}
+    })
+
+    return &Chain{
+        Config:   config,
+        Backend:  backend,
+        Client:   backend.Client(),
+        deployer: deployer,
+        keeper:   keeper,
+        chainID:  big.NewInt(ChainID),
+        logger:   log.New(os.Stdout, "[SimChain] ", log.LstdFlags|log.Lmicroseconds),
+    }, nil
+}
+
+// Endpoint is the WebSocket URL the chain is served on
+func (c *Chain) Endpoint() string {
+    return fmt.Sprintf("ws://%s:%d", c.Config.WSHost, c.Config.WSPort)
+}
+
+// Deploy deploys TwoPhaseCommit from the artifact's bytecode and mines it
+func (c *Chain) Deploy(ctx context.Context, artifactPath string) (common.Address, error) {
+    artifact, err := bindings.ReadArtifact(artifactPath)
+    if err != nil {
+        return common.Address{}, err
+    }
+
+    opts := signer.TransactOpts(ctx, c.deployer, c.chainID)
+    address, tx, err := artifact.Deploy(opts, c.Client)
+    if err != nil {
+        return common.Address{}, fmt.Errorf("failed to deploy %s: %w", artifact.ContractName, err)
+    }
+    c.Mine()
+
+    receipt, err := bind.WaitMined(ctx, c.Client, tx)
+    if err != nil {
+        return common.Address{}, err
+    }
+    if receipt.Status != types.ReceiptStatusSuccessful {
+        return common.Address{}, errors.New("deployment transaction reverted")
+    }
+
+    contract, err := bindings.NewTwoPhaseCommit(address, c.Client)
+    if err != nil {
+        return common.Address{}, err
+    }
+    c.Contract, c.Address, c.Deployed = contract, address, receipt.BlockNumber.Uint64()
+    c.logger.Printf("Deployed %s at %s in block %d", artifact.ContractName, address.Hex(), c.Deployed)
+    return address, nil
+}
+
+// Mine seals the pending transactions into a new block
+func (c *Chain) Mine() common.Hash {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.Backend.Commit()
+}
+
+// AdvanceTime moves the chain clock forward by d, sealing anything pending
+// first because the simulated backend only adjusts time on empty blocks
+func (c *Chain) AdvanceTime(d time.Duration) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    var err error
+    for attempt := 0; attempt < 5; attempt++ {
+        c.Backend.Commit()
+        if err = c.Backend.AdjustTime(d); err == nil {
+            return nil
+        }
+    }
+    return fmt.Errorf("failed to advance time: %w", err)
+}
+
+// Head returns the latest block header
+func (c *Chain) Head(ctx context.Context) (*types.Header, error) {
+    return c.Client.HeaderByNumber(ctx, nil)
+}
+
+// Start mines a block every BlockTime and runs the keeper until ctx is done
+func (c *Chain) Start(ctx context.Context) {
+    if c.Config.BlockTime == 0 {
+        return
+    }
+
+    go func() {
+        ticker := time.NewTicker(c.Config.BlockTime)
+        defer ticker.Stop()
+
+        for {
+            select {
+            case <-ticker.C:
+                c.Mine()
+                if c.Config.Keeper {
+                    c.performUpkeep(ctx)
+                }
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+}
+
+// performUpkeep sends performUpkeep from the keeper account when the
+// contract reports work for the current block
+func (c *Chain) performUpkeep(ctx context.Context) {
+    if c.Contract == nil {
+        return
+    }
+
+    check, err := c.Contract.CheckUpkeep(&bind.CallOpts{Context: ctx}, nil)
+    if err != nil {
+        c.logger.Printf("checkUpkeep failed: %v", err)
+        return
+    }
+    if !check.UpkeepNeeded {
+        return
+    }
+
+    opts := signer.TransactOpts(ctx, c.keeper, c.chainID)
+    tx, err := c.Contract.PerformUpkeep(opts, check.PerformData)
+    if err != nil {
+        c.logger.Printf("performUpkeep failed: %v", err)
+        return
+    }
+    c.logger.Printf("Sent performUpkeep in %s", tx.Hash().Hex())
+}
+
+// Close stops the chain and its RPC server
+func (c *Chain) Close() error {
+    return c.Backend.Close()
+}
+
+// AdminHandler serves the controls tests need that JSON-RPC does not offer:
+// GET /head, POST /mine and POST /advance?seconds=N.
+func (c *Chain) AdminHandler() http.Handler {
+    mux := http.NewServeMux()
+
+    mux.HandleFunc("/head", func(w http.ResponseWriter, r *http.Request) {
+        header, err := c.Head(r.Context())
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        writeJSON(w, map[string]interface{}{
+            "number":    header.Number.Uint64(),
+            "timestamp": header.Time,
+            "contract":  c.Address.Hex(),
+        })
+    })
+
+    mux.HandleFunc("/mine", func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+            http.Error(w, "use POST", http.StatusMethodNotAllowed)
+            return
+        }
+        writeJSON(w, map[string]string{"block": c.Mine().Hex()})
+    })
+
+    mux.HandleFunc("/advance", func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+            http.Error(w, "use POST", http.StatusMethodNotAllowed)
+            return
+        }
+        seconds, err := strconv.ParseUint(r.URL.Query().Get("seconds"), 10, 64)
+        if err != nil {
+            http.Error(w, "seconds must be a positive integer", http.StatusBadRequest)
+            return
+        }
+        if err := c.AdvanceTime(time.Duration(seconds) * time.Second); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        if c.Config.Keeper {
+            c.performUpkeep(r.Context())
+        }
+
+        header, err := c.Head(r.Context())
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        writeJSON(w, map[string]uint64{"number": header.Number.Uint64(), "timestamp": header.Time})
+    })
+
+    return mux
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(value)
+}
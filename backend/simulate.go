@@ -0,0 +1,82 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "log"
+    "net"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "web3server/simchain"
+
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/joho/godotenv"
+)
+
+// runSimulate serves an offline chain with TwoPhaseCommit deployed, for the
+// simulated network profile
+func runSimulate(args []string) {
+    fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+    artifact := fs.String("artifact", "TwoPhaseCommit.json", "Hardhat artifact to deploy")
+    wsAddr := fs.String("ws", "127.0.0.1:8546", "address to serve JSON-RPC over WebSocket on")
+    adminAddr := fs.String("admin", "127.0.0.1:8547", "address to serve the time and mining controls on")
+    fund := fs.String("fund", "", "comma separated accounts to fund besides the configured signer")
+    blockTime := fs.Duration("block-time", time.Second, "interval between mined blocks")
+    keeper := fs.Bool("keeper", true, "call performUpkeep whenever checkUpkeep asks for it")
+    fs.Parse(args)
+
+    host, portText, err := net.SplitHostPort(*wsAddr)
+    if err != nil {
+        log.Fatalf("Invalid -ws address: %v", err)
+    }
+    port, err := strconv.Atoi(portText)
+    if err != nil {
+        log.Fatalf("Invalid -ws port: %v", err)
+    }
+
+    var accounts []common.Address
+    for _, account := range strings.Split(*fund, ",") {
+        if account = strings.TrimSpace(account); account != "" {
+            if !common.IsHexAddress(account) {
+                log.Fatalf("Invalid account to fund: %s", account)
+            }
+            accounts = append(accounts, common.HexToAddress(account))
+        }
+    }
+    // Fund the backend's own signer so it can send transactions unchanged
+    godotenv.Load()
+    if s, err := newSignerFromEnv(context.Background()); err == nil {
+        accounts = append(accounts, s.Address())
+    }
+
+    chain, err := simchain.NewChain(simchain.ChainConfig{
+        WSHost:    host,
+        WSPort:    port,
+        Fund:      accounts,
+        BlockTime: *blockTime,
+        Keeper:    *keeper,
+    })
+    if err != nil {
+        log.Fatalf("Failed to start simulated chain: %v", err)
+    }
+    defer chain.Close()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    address, err := chain.Deploy(ctx, *artifact)
+    if err != nil {
+        log.Fatalf("Failed to deploy contract: %v", err)
+    }
+    chain.Start(ctx)
+
+    log.Printf("Serving chain %d on %s with TwoPhaseCommit at %s", simchain.ChainID, chain.Endpoint(), address.Hex())
+    for _, account := range accounts {
+        log.Printf("Funded %s", account.Hex())
+    }
+    log.Printf("Controls on http://%s (GET /head, POST /mine, POST /advance?seconds=N)", *adminAddr)
+    log.Fatal(http.ListenAndServe(*adminAddr, chain.AdminHandler()))
+}
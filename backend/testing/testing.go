@@ -9,9 +9,9 @@ import (
     "sync"
     "time"
     "math"
-    h "web3server/helper"
+    "web3server/bindings"
+    "web3server/reorg"
     "github.com/ethereum/go-ethereum"
-    "github.com/ethereum/go-ethereum/accounts/abi"
     "github.com/ethereum/go-ethereum/common"
     "github.com/ethereum/go-ethereum/core/types"
     "github.com/ethereum/go-ethereum/ethclient"
@@ -28,7 +28,6 @@ type NetworkCondition struct {
 type TestConfig struct {
     NetworkEndpoint    string
     ContractAddress    common.Address
-    ContractABI        abi.ABI
     NetworkConditions  []NetworkCondition
 }
 
@@ -36,10 +35,11 @@ type TestConfig struct {
 type TestNode struct {
     ID               int
     Client           *ethclient.Client
-    ContractABI      abi.ABI
+    Contract         *bindings.TwoPhaseCommitFilterer
     EventTimes       map[string]time.Time
     EventData        map[string]interface{}
     NetworkCondition NetworkCondition
+    Reorgs           *reorg.Tracker
     mu              sync.RWMutex
     logger          *log.Logger
 }
@@ -61,14 +61,20 @@ func NewDistributedTester(config TestConfig) (*DistributedTester, error) {
         if err != nil {
             return nil, fmt.Errorf("failed to connect node %d: %w", i, err)
         }
+
+        contract, err := bindings.NewTwoPhaseCommitFilterer(config.ContractAddress, client)
+        if err != nil {
+            return nil, fmt.Errorf("failed to bind contract for node %d: %w", i, err)
+        }
         
         nodes = append(nodes, &TestNode{
             ID:               i,
             Client:           client,
-            ContractABI:      config.ContractABI,
+            Contract:         contract,
             EventTimes:       make(map[string]time.Time),
             EventData:        make(map[string]interface{}),
             NetworkCondition: condition,
+            Reorgs:           reorg.NewTracker(),
             logger:          log.New(os.Stdout, fmt.Sprintf("[Node %d] ", i), log.LstdFlags|log.Lmicroseconds),
         })
     }
@@ -180,16 +186,26 @@ func (n *TestNode) handleLog(vLog types.Log) {
     n.simulateNetworkConditions()
 
     receiveTime := time.Now()
+
+    // A removed log was reorged out, so forget it rather than count it. The
+    // head is read before locking so the RPC never holds up other logs.
+    if vLog.Removed {
+        head := n.currentHead(vLog.BlockNumber)
+        n.mu.Lock()
+        defer n.mu.Unlock()
+        n.rollbackLog(vLog, head)
+        return
+    }
+
     n.mu.Lock()
     defer n.mu.Unlock()
     
     txHash := vLog.TxHash.Hex()
     n.EventTimes[txHash] = receiveTime
     
-    switch vLog.Topics[0].Hex() {
-    case n.ContractABI.Events["ReleaseEncryptedData"].ID.Hex():
-        var event h.PushEncrytedDataEvent
-        err := n.ContractABI.UnpackIntoInterface(&event, "ReleaseEncryptedData", vLog.Data)
+    switch vLog.Topics[0] {
+    case bindings.EventID("ReleaseEncryptedData"):
+        event, err := n.Contract.ParseReleaseEncryptedData(vLog)
         if err != nil {
             n.logger.Printf("Failed to unpack ReleaseEncryptedData event: %v", err)
             return
@@ -199,9 +215,8 @@ func (n *TestNode) handleLog(vLog types.Log) {
             event.Owner,
             event.DataName)
             
-    case n.ContractABI.Events["KeyReleased"].ID.Hex():
-        var event h.KeyReleasedEvent
-        err := n.ContractABI.UnpackIntoInterface(&event, "KeyReleased", vLog.Data)
+    case bindings.EventID("KeyReleased"):
+        event, err := n.Contract.ParseKeyReleased(vLog)
         if err != nil {
             n.logger.Printf("Failed to unpack KeyReleased event: %v", err)
             return
@@ -210,9 +225,8 @@ func (n *TestNode) handleLog(vLog types.Log) {
         n.logger.Printf("Received KeyReleased event\nOwner: %s\nDataName: %s",
             event.Owner,
             event.DataName)
-    case n.ContractABI.Events["KeyReleaseRequested"].ID.Hex():
-        var event h.KeyReleaseRequestedEvent
-        err := n.ContractABI.UnpackIntoInterface(&event, "KeyReleaseRequested", vLog.Data)
+    case bindings.EventID("KeyReleaseRequested"):
+        event, err := n.Contract.ParseKeyReleaseRequested(vLog)
         if err != nil {
             n.logger.Printf("Failed to unpack KeyReleaseRequested event: %v", err)
             return
@@ -224,6 +238,41 @@ func (n *TestNode) handleLog(vLog types.Log) {
     }
 }
 
+// currentHead returns the node's latest block, or fallback if it cannot be read
+func (n *TestNode) currentHead(fallback uint64) uint64 {
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    if latest, err := n.Client.BlockNumber(ctx); err == nil {
+        return latest
+    }
+    return fallback
+}
+
+// rollbackLog drops the stats derived from a log the chain no longer has,
+// measuring the reorg depth against head. Callers must hold n.mu.
+func (n *TestNode) rollbackLog(vLog types.Log, head uint64) {
+    txHash := vLog.TxHash.Hex()
+    delete(n.EventTimes, txHash)
+    delete(n.EventData, txHash)
+
+    depth := reorg.Depth(head, vLog.BlockNumber)
+    n.Reorgs.Record(reorg.Reorg{
+        BlockNumber: vLog.BlockNumber,
+        Depth:       depth,
+        TxHash:      txHash,
+    })
+    n.logger.Printf("Log in tx %s removed by reorg at block %d (depth %d)", txHash, vLog.BlockNumber, depth)
+}
+
+// GetReorgStats returns the reorgs observed by each node
+func (dt *DistributedTester) GetReorgStats() map[int]reorg.Stats {
+    stats := make(map[int]reorg.Stats)
+    for _, node := range dt.Nodes {
+        stats[node.ID] = node.Reorgs.Stats()
+    }
+    return stats
+}
+
 // GetEventStats returns timing statistics for events across nodes
 func (dt *DistributedTester) GetEventStats() map[string]struct {
     FirstNode     int
@@ -0,0 +1,157 @@
+package txmgr
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/big"
+    "strings"
+
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/core/types"
+)
+
+// FeeBackend is the part of a client needed to price dynamic-fee transactions
+type FeeBackend interface {
+    SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+    HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// Fees are the EIP-1559 fee parameters chosen for a transaction
+type Fees struct {
+    Strategy  string   `json:"strategy"`
+    BaseFee   *big.Int `json:"baseFee"`
+    GasTipCap *big.Int `json:"maxPriorityFeePerGas"`
+    GasFeeCap *big.Int `json:"maxFeePerGas"`
+}
+
+// Apply sets the fees on transact options so bind builds a type-2 transaction
+func (f Fees) Apply(opts *bind.TransactOpts) {
+    opts.GasPrice = nil
+    opts.GasTipCap = f.GasTipCap
+    opts.GasFeeCap = f.GasFeeCap
+}
+
+// GasStrategy picks the tip and fee cap for the next transaction
+type GasStrategy interface {
+    Name() string
+    Fees(ctx context.Context, backend FeeBackend) (Fees, error)
+}
+
+// multiplierStrategy scales the suggested tip and leaves headroom for the
+// base fee to rise over a number of full blocks.
+type multiplierStrategy struct {
+    name string
+    // tip as a percentage of the node's suggestion
+    tipPercent int64
+    // fee cap as a percentage of the current base fee, before adding the tip
+    baseFeePercent int64
+}
+
+var (
+    // Fast pays a higher tip and survives several full blocks of base fee growth
+    Fast GasStrategy = multiplierStrategy{name: "fast", tipPercent: 150, baseFeePercent: 300}
+    // Normal follows the node's suggestion with the usual 2x base fee headroom
+    Normal GasStrategy = multiplierStrategy{name: "normal", tipPercent: 100, baseFeePercent: 200}
+    // Economical tips below the suggestion and tolerates only a small base fee rise
+    Economical GasStrategy = multiplierStrategy{name: "economical", tipPercent: 80, baseFeePercent: 125}
+)
+
+func (s multiplierStrategy) Name() string {
+    return s.name
+}
+
+func (s multiplierStrategy) Fees(ctx context.Context, backend FeeBackend) (Fees, error) {
+    header, err := backend.HeaderByNumber(ctx, nil)
+    if err != nil {
+        return Fees{}, fmt.Errorf("failed to fetch latest header: %w", err)
+    }
+    if header.BaseFee == nil {
+        return Fees{}, errors.New("chain does not support dynamic-fee transactions")
+    }
+
+    suggestedTip, err := backend.SuggestGasTipCap(ctx)
+    if err != nil {
+        return Fees{}, fmt.Errorf("failed to suggest gas tip cap: %w", err)
+    }
+
+    tip := percentOf(suggestedTip, s.tipPercent)
+    feeCap := new(big.Int).Add(percentOf(header.BaseFee, s.baseFeePercent), tip)
+
+    return Fees{
+        Strategy:  s.name,
+        BaseFee:   new(big.Int).Set(header.BaseFee),
+        GasTipCap: tip,
+        GasFeeCap: feeCap,
+    }, nil
+}
+
+// cappedStrategy never lets another strategy exceed a maximum fee per gas
+type cappedStrategy struct {
+    inner     GasStrategy
+    maxFeeCap *big.Int
+}
+
+// Capped limits the fee cap chosen by inner to maxFeeCap
+func Capped(inner GasStrategy, maxFeeCap *big.Int) GasStrategy {
+    return cappedStrategy{inner: inner, maxFeeCap: maxFeeCap}
+}
+
+func (s cappedStrategy) Name() string {
+    return s.inner.Name() + "-capped"
+}
+
+func (s cappedStrategy) Fees(ctx context.Context, backend FeeBackend) (Fees, error) {
+    fees, err := s.inner.Fees(ctx, backend)
+    if err != nil {
+        return Fees{}, err
+    }
+    if fees.BaseFee.Cmp(s.maxFeeCap) >= 0 {
+        return Fees{}, fmt.Errorf("base fee %s wei is above the %s wei cap", fees.BaseFee, s.maxFeeCap)
+    }
+
+    fees.Strategy = s.Name()
+    if fees.GasFeeCap.Cmp(s.maxFeeCap) > 0 {
+        fees.GasFeeCap = new(big.Int).Set(s.maxFeeCap)
+    }
+    if fees.GasTipCap.Cmp(fees.GasFeeCap) > 0 {
+        fees.GasTipCap = new(big.Int).Set(fees.GasFeeCap)
+    }
+    return fees, nil
+}
+
+// StrategyByName returns fast, normal or economical, capped at maxFeeCap
+// when it is non-nil
+func StrategyByName(name string, maxFeeCap *big.Int) (GasStrategy, error) {
+    var strategy GasStrategy
+    switch strings.ToLower(name) {
+    case "fast":
+        strategy = Fast
+    case "", "normal":
+        strategy = Normal
+    case "economical":
+        strategy = Economical
+    default:
+        return nil, fmt.Errorf("unknown gas strategy %q, expected fast, normal or economical", name)
+    }
+
+    if maxFeeCap != nil && maxFeeCap.Sign() > 0 {
+        strategy = Capped(strategy, maxFeeCap)
+    }
+    return strategy, nil
+}
+
+func percentOf(value *big.Int, percent int64) *big.Int {
+    result := new(big.Int).Mul(value, big.NewInt(percent))
+    return result.Div(result, big.NewInt(100))
+}
+
+// ParseGwei converts a decimal gwei amount such as "25" or "1.5" to wei
+func ParseGwei(value string) (*big.Int, error) {
+    gwei, ok := new(big.Float).SetString(value)
+    if !ok || gwei.Sign() < 0 {
+        return nil, fmt.Errorf("invalid gwei amount %q", value)
+    }
+    wei, _ := new(big.Float).Mul(gwei, big.NewFloat(1e9)).Int(nil)
+    return wei, nil
+}
@@ -0,0 +1,250 @@
+package txmgr
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/rpc"
+)
+
+// NonceBackend is the part of a client needed to track account nonces
+type NonceBackend interface {
+    PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// GapFiller spends a nonce that no pending send is going to use
+type GapFiller func(ctx context.Context, nonce uint64) error
+
+// NonceManager hands out nonces for one signer locally, so concurrent sends
+// never race on PendingNonceAt. It resyncs from the chain on startup, on
+// nonce errors and periodically, and reuses nonces left behind by failed
+// sends before allocating new ones.
+type NonceManager struct {
+    backend NonceBackend
+    address common.Address
+
+    mu       sync.Mutex
+    next     uint64
+    inFlight map[uint64]bool
+    sent     map[uint64]bool
+    gaps     map[uint64]time.Time
+    logger   *log.Logger
+}
+
+// NewNonceManager creates a manager for address and syncs it from the chain
+func NewNonceManager(ctx context.Context, backend NonceBackend, address common.Address) (*NonceManager, error) {
+    m := &NonceManager{
+        backend:  backend,
+        address:  address,
+        inFlight: make(map[uint64]bool),
+        sent:     make(map[uint64]bool),
+        gaps:     make(map[uint64]time.Time),
+        logger:   log.New(os.Stdout, "[Nonce] ", log.LstdFlags|log.Lmicroseconds),
+    }
+    if err := m.Sync(ctx); err != nil {
+        return nil, err
+    }
+    return m, nil
+}
+
+// Next reserves the lowest free nonce. Every reserved nonce must be settled
+// with Sent or Failed.
+func (m *NonceManager) Next(ctx context.Context) (uint64, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    if gap, ok := m.lowestGap(); ok {
+        delete(m.gaps, gap)
+        m.inFlight[gap] = true
+        return gap, nil
+    }
+
+    nonce := m.next
+    m.next++
+    m.inFlight[nonce] = true
+    return nonce, nil
+}
+
+// Sent records that a transaction with nonce was accepted by the node
+func (m *NonceManager) Sent(nonce uint64) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    delete(m.inFlight, nonce)
+    m.sent[nonce] = true
+}
+
+// Failed releases a nonce whose send returned an error. Only a rejection
+// from the node proves the nonce is unused; after a timeout or transport
+// error the node may still have taken the transaction, so the manager
+// resyncs and lets the pending nonce decide. Nonce errors mean the local
+// view is stale and resync too, and a rejection because the pool already
+// holds a transaction with the nonce keeps it marked as sent.
+func (m *NonceManager) Failed(ctx context.Context, nonce uint64, sendErr error) {
+    queued := IsNonceQueued(sendErr)
+    unused := IsRejected(sendErr) && !IsNonceTooLow(sendErr) && !queued
+
+    m.mu.Lock()
+    delete(m.inFlight, nonce)
+    if unused {
+        // The nonce is still unused and must be filled before later ones mine
+        m.gaps[nonce] = time.Now()
+    }
+    if queued {
+        m.sent[nonce] = true
+    }
+    m.mu.Unlock()
+
+    if !unused || IsNonceTooHigh(sendErr) {
+        if err := m.Sync(ctx); err != nil {
+            m.logger.Printf("Failed to resync after send error: %v", err)
+        }
+    }
+}
+
+// Sync reconciles the local view with the chain's pending nonce. Nonces
+// below it are settled; nonces between it and the next local nonce that are
+// neither in flight nor known to be sent are gaps.
+func (m *NonceManager) Sync(ctx context.Context) error {
+    pending, err := m.backend.PendingNonceAt(ctx, m.address)
+    if err != nil {
+        return fmt.Errorf("failed to retrieve account nonce: %w", err)
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    for nonce := range m.sent {
+        if nonce < pending {
+            delete(m.sent, nonce)
+        }
+    }
+    for nonce := range m.gaps {
+        if nonce < pending {
+            delete(m.gaps, nonce)
+        }
+    }
+
+    if pending > m.next {
+        // Someone else used this account, or state was lost across a restart
+        m.next = pending
+        return nil
+    }
+
+    for nonce := pending; nonce < m.next; nonce++ {
+        if m.inFlight[nonce] || m.sent[nonce] {
+            continue
+        }
+        if _, known := m.gaps[nonce]; !known {
+            m.logger.Printf("Detected nonce gap at %d", nonce)
+            m.gaps[nonce] = time.Now()
+        }
+    }
+    return nil
+}
+
+// Gaps lists the nonces currently waiting to be reused
+func (m *NonceManager) Gaps() []uint64 {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    gaps := make([]uint64, 0, len(m.gaps))
+    for nonce := range m.gaps {
+        gaps = append(gaps, nonce)
+    }
+    sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+    return gaps
+}
+
+// Start periodically resyncs and fills any gap that no send has reused
+// within maxAge, so later transactions are not stuck behind it.
+func (m *NonceManager) Start(ctx context.Context, interval, maxAge time.Duration, fill GapFiller) {
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+
+        for {
+            select {
+            case <-ticker.C:
+                if err := m.Sync(ctx); err != nil {
+                    m.logger.Printf("Failed to resync: %v", err)
+                    continue
+                }
+                m.fillStaleGaps(ctx, maxAge, fill)
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+}
+
+func (m *NonceManager) fillStaleGaps(ctx context.Context, maxAge time.Duration, fill GapFiller) {
+    m.mu.Lock()
+    var stale []uint64
+    for nonce, since := range m.gaps {
+        if time.Since(since) >= maxAge {
+            stale = append(stale, nonce)
+            delete(m.gaps, nonce)
+            m.inFlight[nonce] = true
+        }
+    }
+    m.mu.Unlock()
+
+    for _, nonce := range stale {
+        err := fill(ctx, nonce)
+        if err != nil {
+            m.logger.Printf("Failed to fill nonce gap at %d: %v", nonce, err)
+            m.Failed(ctx, nonce, err)
+            continue
+        }
+        m.logger.Printf("Filled nonce gap at %d", nonce)
+        m.Sent(nonce)
+    }
+}
+
+func (m *NonceManager) lowestGap() (uint64, bool) {
+    found := false
+    var lowest uint64
+    for nonce := range m.gaps {
+        if !found || nonce < lowest {
+            lowest, found = nonce, true
+        }
+    }
+    return lowest, found
+}
+
+// IsRejected reports whether a send error came back from a node that
+// handled and refused the transaction, rather than from the transport
+func IsRejected(err error) bool {
+    var rpcErr rpc.Error
+    return errors.As(err, &rpcErr)
+}
+
+// IsNonceTooLow reports whether a send failed because the nonce was used.
+// Node errors arrive as plain strings over RPC, so match on the message.
+func IsNonceTooLow(err error) bool {
+    return err != nil && strings.Contains(strings.ToLower(err.Error()), "nonce too low")
+}
+
+// IsNonceQueued reports whether a send failed because the node's pool
+// already holds a transaction with the same nonce
+func IsNonceQueued(err error) bool {
+    if err == nil {
+        return false
+    }
+    msg := strings.ToLower(err.Error())
+    return strings.Contains(msg, "replacement transaction underpriced") || strings.Contains(msg, "already known")
+}
+
+// IsNonceTooHigh reports whether a send failed because earlier nonces are missing
+func IsNonceTooHigh(err error) bool {
+    return err != nil && strings.Contains(strings.ToLower(err.Error()), "nonce too high")
+}
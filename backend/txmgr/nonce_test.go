@@ -0,0 +1,93 @@
+package txmgr
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "testing"
+
+    "github.com/ethereum/go-ethereum/common"
+)
+
+// fakeNonces reports a pending nonce the test controls
+type fakeNonces struct {
+    mu      sync.Mutex
+    pending uint64
+}
+
+func (f *fakeNonces) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.pending, nil
+}
+
+func (f *fakeNonces) set(pending uint64) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.pending = pending
+}
+
+// nodeError is an error returned by a node over JSON-RPC
+type nodeError string
+
+func (e nodeError) Error() string  { return string(e) }
+func (e nodeError) ErrorCode() int { return -32000 }
+
+func newTestNonces(t *testing.T, backend *fakeNonces) *NonceManager {
+    manager, err := NewNonceManager(context.Background(), backend, common.Address{})
+    if err != nil {
+        t.Fatalf("NewNonceManager: %v", err)
+    }
+    return manager
+}
+
+func TestFailedRejectionLeavesGap(t *testing.T) {
+    manager := newTestNonces(t, &fakeNonces{})
+
+    nonce, _ := manager.Next(context.Background())
+    manager.Failed(context.Background(), nonce, nodeError("insufficient funds for gas * price + value"))
+
+    if gaps := manager.Gaps(); len(gaps) != 1 || gaps[0] != nonce {
+        t.Fatalf("got gaps %v, want [%d]", gaps, nonce)
+    }
+    if reused, _ := manager.Next(context.Background()); reused != nonce {
+        t.Errorf("got nonce %d, want the rejected nonce %d reused", reused, nonce)
+    }
+}
+
+func TestFailedReplacementUnderpricedKeepsNonce(t *testing.T) {
+    for _, pending := range []uint64{1, 0} {
+        backend := &fakeNonces{}
+        manager := newTestNonces(t, backend)
+
+        nonce, _ := manager.Next(context.Background())
+        // The pool holds another transaction at the nonce; the node may or
+        // may not count it in the pending nonce yet
+        backend.set(pending)
+        manager.Failed(context.Background(), nonce, nodeError("replacement transaction underpriced"))
+
+        if gaps := manager.Gaps(); len(gaps) != 0 {
+            t.Errorf("pending %d: got gaps %v, want none", pending, gaps)
+        }
+        if next, _ := manager.Next(context.Background()); next == nonce {
+            t.Errorf("pending %d: nonce %d held by the pool was handed out again", pending, nonce)
+        }
+    }
+}
+
+func TestFailedTransportErrorResyncs(t *testing.T) {
+    backend := &fakeNonces{}
+    manager := newTestNonces(t, backend)
+
+    nonce, _ := manager.Next(context.Background())
+    // The node took the transaction before the connection dropped
+    backend.set(1)
+    manager.Failed(context.Background(), nonce, errors.New("connection reset by peer"))
+
+    if gaps := manager.Gaps(); len(gaps) != 0 {
+        t.Errorf("got gaps %v, want none", gaps)
+    }
+    if next, _ := manager.Next(context.Background()); next != 1 {
+        t.Errorf("got nonce %d, want 1", next)
+    }
+}
@@ -0,0 +1,39 @@
+package txmgr
+
+import (
+    "context"
+    "math/big"
+
+    "web3server/signer"
+
+    "github.com/ethereum/go-ethereum/core/types"
+)
+
+// SendBackend is the part of a client needed to submit transactions
+type SendBackend interface {
+    SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+// SelfTransfer sends a zero-value transfer to the signer's own address. It
+// is the cheapest way to consume a nonce, e.g. to fill a gap.
+func SelfTransfer(ctx context.Context, backend SendBackend, s signer.Signer, chainID *big.Int, nonce uint64, fees Fees) (*types.Transaction, error) {
+    to := s.Address()
+    tx := types.NewTx(&types.DynamicFeeTx{
+        ChainID:   chainID,
+        Nonce:     nonce,
+        GasTipCap: fees.GasTipCap,
+        GasFeeCap: fees.GasFeeCap,
+        Gas:       21000,
+        To:        &to,
+        Value:     big.NewInt(0),
+    })
+
+    signedTx, err := s.SignTx(ctx, tx, chainID)
+    if err != nil {
+        return nil, err
+    }
+    if err := backend.SendTransaction(ctx, signedTx); err != nil {
+        return nil, err
+    }
+    return signedTx, nil
+}
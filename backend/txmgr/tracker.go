@@ -0,0 +1,341 @@
+package txmgr
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "math/big"
+    "os"
+    "sort"
+    "sync"
+    "time"
+
+    "web3server/signer"
+
+    "github.com/ethereum/go-ethereum"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/core/types"
+)
+
+var ErrTxNotTracked = errors.New("transaction is not being tracked")
+
+// minBumpPercent is the smallest fee increase nodes accept for a replacement
+const minBumpPercent = 10
+
+// TrackerBackend is the part of a client needed to watch and replace transactions
+type TrackerBackend interface {
+    SendBackend
+    HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+    NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+    TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// TrackerConfig holds the settings for replacing stuck transactions
+type TrackerConfig struct {
+    Client       TrackerBackend
+    Signer       signer.Signer
+    ChainID      *big.Int
+    StuckTimeout time.Duration
+    PollInterval time.Duration
+    BumpPercent  int64
+    MaxFeeCap    *big.Int
+    // OnReplaced is called whenever a transaction is re-sent under a new
+    // hash; cancelled is set when the replacement is a cancelling self-transfer
+    OnReplaced func(oldHash, newHash common.Hash, cancelled bool)
+}
+
+// Pending is an outstanding transaction together with every replacement
+// sent for its nonce.
+type Pending struct {
+    Label        string        `json:"label"`
+    Nonce        uint64        `json:"nonce"`
+    Hash         common.Hash   `json:"transactionHash"`
+    Replaced     []common.Hash `json:"replaced,omitempty"`
+    GasTipCap    *big.Int      `json:"maxPriorityFeePerGas"`
+    GasFeeCap    *big.Int      `json:"maxFeePerGas"`
+    Cancelled    bool          `json:"cancelled"`
+    FirstSentAt  time.Time     `json:"firstSentAt"`
+    LastSentAt   time.Time     `json:"lastSentAt"`
+    Replacements int           `json:"replacements"`
+
+    tx *types.Transaction
+}
+
+// hashes lists the current and all earlier hashes for the nonce
+func (p *Pending) hashes() []common.Hash {
+    return append([]common.Hash{p.Hash}, p.Replaced...)
+}
+
+// Tracker watches outstanding transactions and re-sends them with the same
+// nonce and higher fees when they sit unmined for too long.
+type Tracker struct {
+    Config  TrackerConfig
+    mu      sync.Mutex
+    pending map[uint64]*Pending
+    logger  *log.Logger
+}
+
+// NewTracker creates a tracker for transactions sent by config.Signer
+func NewTracker(config TrackerConfig) (*Tracker, error) {
+    if config.Client == nil || config.Signer == nil {
+        return nil, errors.New("tracker requires a client and signer")
+    }
+    if config.ChainID == nil {
+        return nil, errors.New("tracker requires a chain ID")
+    }
+    if config.StuckTimeout == 0 {
+        config.StuckTimeout = 3 * time.Minute
+    }
+    if config.PollInterval == 0 {
+        config.PollInterval = 15 * time.Second
+    }
+    if config.BumpPercent == 0 {
+        config.BumpPercent = 20
+    }
+    if config.BumpPercent < minBumpPercent {
+        return nil, fmt.Errorf("fee bump must be at least %d%%", minBumpPercent)
+    }
+
+    return &Tracker{
+        Config:  config,
+        pending: make(map[uint64]*Pending),
+        logger:  log.New(os.Stdout, "[TxTracker] ", log.LstdFlags|log.Lmicroseconds),
+    }, nil
+}
+
+// Watch starts tracking a transaction that has just been sent
+func (t *Tracker) Watch(tx *types.Transaction, label string) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    now := time.Now()
+    t.pending[tx.Nonce()] = &Pending{
+        Label:       label,
+        Nonce:       tx.Nonce(),
+        Hash:        tx.Hash(),
+        GasTipCap:   tx.GasTipCap(),
+        GasFeeCap:   tx.GasFeeCap(),
+        FirstSentAt: now,
+        LastSentAt:  now,
+        tx:          tx,
+    }
+}
+
+// Pending lists the tracked transactions ordered by nonce
+func (t *Tracker) Pending() []Pending {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    list := make([]Pending, 0, len(t.pending))
+    for _, p := range t.pending {
+        list = append(list, *p)
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i].Nonce < list[j].Nonce })
+    return list
+}
+
+// SpeedUp re-sends the transaction known by hash with escalated fees
+func (t *Tracker) SpeedUp(ctx context.Context, hash common.Hash) (Pending, error) {
+    p, err := t.find(hash)
+    if err != nil {
+        return Pending{}, err
+    }
+    return t.replace(ctx, p, false)
+}
+
+// Cancel replaces the transaction known by hash with a zero-value transfer
+// to the signer itself, so its nonce is spent without running the call.
+func (t *Tracker) Cancel(ctx context.Context, hash common.Hash) (Pending, error) {
+    p, err := t.find(hash)
+    if err != nil {
+        return Pending{}, err
+    }
+    return t.replace(ctx, p, true)
+}
+
+// Wait blocks until one of the transactions sent for hash's nonce is mined,
+// following any replacements made in the meantime.
+func (t *Tracker) Wait(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+    ticker := time.NewTicker(time.Second)
+    defer ticker.Stop()
+
+    for {
+        receipt, err := t.Receipt(ctx, hash)
+        if !errors.Is(err, ethereum.NotFound) {
+            return receipt, err
+        }
+
+        select {
+        case <-ticker.C:
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        }
+    }
+}
+
+// Receipt returns the receipt of whichever transaction sent for hash's nonce
+// has been mined, or ethereum.NotFound while none has
+func (t *Tracker) Receipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+    hashes := []common.Hash{hash}
+    if p, err := t.find(hash); err == nil {
+        hashes = p.hashes()
+    }
+
+    for _, candidate := range hashes {
+        receipt, err := t.Config.Client.TransactionReceipt(ctx, candidate)
+        if !errors.Is(err, ethereum.NotFound) {
+            return receipt, err
+        }
+    }
+    return nil, ethereum.NotFound
+}
+
+// Start checks tracked transactions in the background until ctx is cancelled
+func (t *Tracker) Start(ctx context.Context) {
+    go func() {
+        ticker := time.NewTicker(t.Config.PollInterval)
+        defer ticker.Stop()
+
+        for {
+            select {
+            case <-ticker.C:
+                t.check(ctx)
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+}
+
+// check forgets nonces that have been mined and bumps the ones that are stuck
+func (t *Tracker) check(ctx context.Context) {
+    confirmed, err := t.Config.Client.NonceAt(ctx, t.Config.Signer.Address(), nil)
+    if err != nil {
+        t.logger.Printf("Failed to retrieve account nonce: %v", err)
+        return
+    }
+
+    var stuck []*Pending
+    t.mu.Lock()
+    for nonce, p := range t.pending {
+        if nonce < confirmed {
+            delete(t.pending, nonce)
+            continue
+        }
+        if time.Since(p.LastSentAt) >= t.Config.StuckTimeout {
+            stuck = append(stuck, p)
+        }
+    }
+    t.mu.Unlock()
+
+    for _, p := range stuck {
+        t.logger.Printf("Transaction %s (%s, nonce %d) unmined after %s, speeding up",
+            p.Hash.Hex(), p.Label, p.Nonce, time.Since(p.LastSentAt).Round(time.Second))
+        if _, err := t.replace(ctx, p, p.Cancelled); err != nil {
+            t.logger.Printf("Failed to replace transaction %s: %v", p.Hash.Hex(), err)
+        }
+    }
+}
+
+// replace signs and sends a copy of p's transaction with bumped fees
+func (t *Tracker) replace(ctx context.Context, p *Pending, cancel bool) (Pending, error) {
+    header, err := t.Config.Client.HeaderByNumber(ctx, nil)
+    if err != nil {
+        return Pending{}, fmt.Errorf("failed to fetch latest header: %w", err)
+    }
+
+    t.mu.Lock()
+    old := p.tx
+    t.mu.Unlock()
+
+    tip, feeCap, err := t.bumpFees(old, header.BaseFee)
+    if err != nil {
+        return Pending{}, err
+    }
+
+    var replacement *types.Transaction
+    if cancel {
+        replacement, err = SelfTransfer(ctx, t.Config.Client, t.Config.Signer, t.Config.ChainID, p.Nonce,
+            Fees{Strategy: "replacement", BaseFee: header.BaseFee, GasTipCap: tip, GasFeeCap: feeCap})
+        if err != nil {
+            return Pending{}, fmt.Errorf("failed to send cancellation: %w", err)
+        }
+    } else {
+        unsigned := types.NewTx(&types.DynamicFeeTx{
+            ChainID:    t.Config.ChainID,
+            Nonce:      old.Nonce(),
+            GasTipCap:  tip,
+            GasFeeCap:  feeCap,
+            Gas:        old.Gas(),
+            To:         old.To(),
+            Value:      old.Value(),
+            Data:       old.Data(),
+            AccessList: old.AccessList(),
+        })
+        replacement, err = t.Config.Signer.SignTx(ctx, unsigned, t.Config.ChainID)
+        if err != nil {
+            return Pending{}, fmt.Errorf("failed to sign replacement: %w", err)
+        }
+        if err := t.Config.Client.SendTransaction(ctx, replacement); err != nil {
+            return Pending{}, fmt.Errorf("failed to send replacement: %w", err)
+        }
+    }
+
+    t.mu.Lock()
+    oldHash := p.Hash
+    p.Replaced = append(p.Replaced, oldHash)
+    p.Hash = replacement.Hash()
+    p.GasTipCap, p.GasFeeCap = tip, feeCap
+    p.Cancelled = p.Cancelled || cancel
+    p.LastSentAt = time.Now()
+    p.Replacements++
+    p.tx = replacement
+    snapshot := *p
+    t.mu.Unlock()
+
+    t.logger.Printf("Replaced %s with %s (nonce %d, tip %s, fee cap %s)",
+        oldHash.Hex(), replacement.Hash().Hex(), p.Nonce, tip, feeCap)
+    if t.Config.OnReplaced != nil {
+        t.Config.OnReplaced(oldHash, replacement.Hash(), snapshot.Cancelled)
+    }
+    return snapshot, nil
+}
+
+// bumpFees raises both fees by at least BumpPercent, and the fee cap further
+// if the base fee has outgrown it.
+func (t *Tracker) bumpFees(tx *types.Transaction, baseFee *big.Int) (*big.Int, *big.Int, error) {
+    tip := percentOf(tx.GasTipCap(), 100+t.Config.BumpPercent)
+    feeCap := percentOf(tx.GasFeeCap(), 100+t.Config.BumpPercent)
+    if baseFee != nil {
+        if floor := new(big.Int).Add(percentOf(baseFee, 200), tip); feeCap.Cmp(floor) < 0 {
+            feeCap = floor
+        }
+    }
+
+    if t.Config.MaxFeeCap != nil && feeCap.Cmp(t.Config.MaxFeeCap) > 0 {
+        // Clamping is only useful while it still clears the node's replacement rule
+        feeCap = new(big.Int).Set(t.Config.MaxFeeCap)
+        if feeCap.Cmp(percentOf(tx.GasFeeCap(), 100+minBumpPercent)) < 0 {
+            return nil, nil, fmt.Errorf("fee cap %s already at the configured maximum", tx.GasFeeCap())
+        }
+        if tip.Cmp(feeCap) > 0 {
+            tip = new(big.Int).Set(feeCap)
+        }
+    }
+    return tip, feeCap, nil
+}
+
+func (t *Tracker) find(hash common.Hash) (*Pending, error) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    for _, p := range t.pending {
+        for _, known := range p.hashes() {
+            if known == hash {
+                return p, nil
+            }
+        }
+    }
+    return nil, ErrTxNotTracked
+}
@@ -0,0 +1,373 @@
+package upkeep
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "math/big"
+    "os"
+    "sync"
+    "time"
+
+    "web3server/bindings"
+    "web3server/signer"
+    "web3server/txmgr"
+
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/core/types"
+)
+
+// phaseOneLead is how long before releaseTime the contract starts phase 1
+const phaseOneLead = 43200
+
+// maxRecent bounds how many transitions are kept for the API
+const maxRecent = 100
+
+// Backend is the part of a client needed to check, send and wait for upkeeps
+type Backend interface {
+    bind.DeployBackend
+    txmgr.FeeBackend
+    BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// RunnerConfig holds everything the runner needs to send performUpkeep
+type RunnerConfig struct {
+    Client       Backend
+    Contract     *bindings.TwoPhaseCommit
+    Signer       signer.Signer
+    ChainID      *big.Int
+    GasStrategy  txmgr.GasStrategy
+    Nonces       *txmgr.NonceManager
+    Transactions *txmgr.Tracker
+    PollInterval time.Duration
+    RetryDelay   time.Duration
+    MaxBackoff   time.Duration
+    MineTimeout  time.Duration
+}
+
+// Transition is one phase change fired by a performUpkeep the runner sent
+type Transition struct {
+    Owner       string    `json:"owner"`
+    DataName    string    `json:"dataName"`
+    Phase       int       `json:"phase"`
+    Target      uint64    `json:"target"`
+    FiredAt     uint64    `json:"firedAt"`
+    LateSeconds int64     `json:"lateSeconds"`
+    BlockNumber uint64    `json:"blockNumber"`
+    TxHash      string    `json:"transactionHash"`
+    ObservedAt  time.Time `json:"observedAt"`
+}
+
+// PhaseMetrics summarizes how late one phase fires
+type PhaseMetrics struct {
+    Count           int     `json:"count"`
+    LastLateSeconds int64   `json:"lastLateSeconds"`
+    MaxLateSeconds  int64   `json:"maxLateSeconds"`
+    MeanLateSeconds float64 `json:"meanLateSeconds"`
+
+    totalLate int64
+}
+
+func (m *PhaseMetrics) observe(late int64) {
+    m.Count++
+    m.totalLate += late
+    m.LastLateSeconds = late
+    if m.Count == 1 || late > m.MaxLateSeconds {
+        m.MaxLateSeconds = late
+    }
+    m.MeanLateSeconds = float64(m.totalLate) / float64(m.Count)
+}
+
+// Metrics is the runner's view of its own upkeeps
+type Metrics struct {
+    LastCheckedBlock    uint64       `json:"lastCheckedBlock"`
+    Performed           int          `json:"performed"`
+    Reverted            int          `json:"reverted"`
+    Failed              int          `json:"failed"`
+    ConsecutiveFailures int          `json:"consecutiveFailures"`
+    LastError           string       `json:"lastError,omitempty"`
+    NextAttempt         time.Time    `json:"nextAttempt"`
+    PhaseOne            PhaseMetrics `json:"phaseOne"`
+    PhaseTwo            PhaseMetrics `json:"phaseTwo"`
+    Recent              []Transition `json:"recent"`
+}
+
+// Runner stands in for Chainlink Automation: it calls checkUpkeep on every
+// new block and sends performUpkeep when the contract asks for it.
+type Runner struct {
+    Config  RunnerConfig
+    metrics Metrics
+    mu      sync.RWMutex
+    wg      sync.WaitGroup
+    logger  *log.Logger
+}
+
+// NewRunner creates a runner, filling in defaults for unset timings
+func NewRunner(config RunnerConfig) (*Runner, error) {
+    if config.Client == nil || config.Contract == nil {
+        return nil, errors.New("upkeep runner requires a client and contract")
+    }
+    if config.Signer == nil || config.Nonces == nil {
+        return nil, errors.New("upkeep runner requires a signer and nonce manager")
+    }
+    if config.GasStrategy == nil {
+        config.GasStrategy = txmgr.Normal
+    }
+    if config.PollInterval == 0 {
+        config.PollInterval = 2 * time.Second
+    }
+    if config.RetryDelay == 0 {
+        config.RetryDelay = 10 * time.Second
+    }
+    if config.MaxBackoff == 0 {
+        config.MaxBackoff = 5 * time.Minute
+    }
+    if config.MineTimeout == 0 {
+        config.MineTimeout = 2 * time.Minute
+    }
+
+    return &Runner{
+        Config:  config,
+        metrics: Metrics{Recent: []Transition{}},
+        logger:  log.New(os.Stdout, "[Upkeep] ", log.LstdFlags|log.Lmicroseconds),
+    }, nil
+}
+
+// Start checks for upkeep on each new block until the context is cancelled
+func (u *Runner) Start(ctx context.Context) {
+    u.wg.Add(1)
+    go func() {
+        defer u.wg.Done()
+
+        ticker := time.NewTicker(u.Config.PollInterval)
+        defer ticker.Stop()
+
+        for {
+            select {
+            case <-ticker.C:
+                u.tick(ctx)
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+}
+
+// Wait blocks until the upkeep loop has exited
+func (u *Runner) Wait() {
+    u.wg.Wait()
+}
+
+// Metrics returns a copy of the runner's counters and lateness figures
+func (u *Runner) Metrics() Metrics {
+    u.mu.RLock()
+    defer u.mu.RUnlock()
+
+    metrics := u.metrics
+    metrics.Recent = append([]Transition{}, u.metrics.Recent...)
+    return metrics
+}
+
+// tick runs one checkUpkeep for a block not checked yet
+func (u *Runner) tick(ctx context.Context) {
+    block, err := u.Config.Client.BlockNumber(ctx)
+    if err != nil {
+        u.logger.Printf("Failed to retrieve head block: %v", err)
+        return
+    }
+
+    u.mu.RLock()
+    skip := block <= u.metrics.LastCheckedBlock || time.Now().Before(u.metrics.NextAttempt)
+    u.mu.RUnlock()
+    if skip {
+        return
+    }
+
+    check, err := u.Config.Contract.CheckUpkeep(&bind.CallOpts{Context: ctx}, nil)
+    if err != nil {
+        u.logger.Printf("checkUpkeep failed: %v", err)
+        return
+    }
+    u.mu.Lock()
+    u.metrics.LastCheckedBlock = block
+    u.mu.Unlock()
+    if !check.UpkeepNeeded {
+        return
+    }
+
+    receipt, err := u.perform(ctx, check.PerformData)
+    if err != nil {
+        u.fail(err, receipt != nil)
+        return
+    }
+    u.record(ctx, receipt)
+}
+
+// perform sends performUpkeep and waits for it to be mined. A receipt is
+// returned alongside the error when the transaction reverted.
+func (u *Runner) perform(ctx context.Context, performData []byte) (*types.Receipt, error) {
+    fees, err := u.Config.GasStrategy.Fees(ctx, u.Config.Client)
+    if err != nil {
+        return nil, fmt.Errorf("failed to price transaction: %v", err)
+    }
+
+    opts := signer.TransactOpts(ctx, u.Config.Signer, u.Config.ChainID)
+    fees.Apply(opts)
+
+    performUpkeep := func(opts *bind.TransactOpts) (*types.Transaction, error) {
+        return u.Config.Contract.PerformUpkeep(opts, performData)
+    }
+
+    // A failing estimate means the call would revert, e.g. another keeper won
+    gasLimit, err := bindings.EstimateGas(opts, performUpkeep)
+    if err != nil {
+        return nil, fmt.Errorf("failed to estimate gas limit: %v", err)
+    }
+    opts.GasLimit = uint64(float64(gasLimit) * 1.1)
+
+    nonce, err := u.Config.Nonces.Next(ctx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to reserve account nonce: %v", err)
+    }
+    opts.Nonce = new(big.Int).SetUint64(nonce)
+
+    signedTx, err := performUpkeep(opts)
+    if err != nil {
+        u.Config.Nonces.Failed(ctx, nonce, err)
+        return nil, fmt.Errorf("failed to send transaction: %v", err)
+    }
+    u.Config.Nonces.Sent(nonce)
+    if u.Config.Transactions != nil {
+        u.Config.Transactions.Watch(signedTx, "performUpkeep")
+    }
+
+    mineCtx, cancel := context.WithTimeout(ctx, u.Config.MineTimeout)
+    defer cancel()
+
+    var receipt *types.Receipt
+    if u.Config.Transactions != nil {
+        receipt, err = u.Config.Transactions.Wait(mineCtx, signedTx.Hash())
+    } else {
+        receipt, err = bind.WaitMined(mineCtx, u.Config.Client, signedTx)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to get transaction receipt: %v", err)
+    }
+    if receipt.Status != types.ReceiptStatusSuccessful {
+        return receipt, fmt.Errorf("performUpkeep transaction reverted in block %d", receipt.BlockNumber.Uint64())
+    }
+    return receipt, nil
+}
+
+// fail backs off exponentially, capped at MaxBackoff
+func (u *Runner) fail(err error, reverted bool) {
+    u.mu.Lock()
+    defer u.mu.Unlock()
+
+    u.metrics.ConsecutiveFailures++
+    u.metrics.LastError = err.Error()
+    if reverted {
+        u.metrics.Reverted++
+    } else {
+        u.metrics.Failed++
+    }
+
+    backoff := u.Config.RetryDelay << (u.metrics.ConsecutiveFailures - 1)
+    if backoff <= 0 || backoff > u.Config.MaxBackoff {
+        backoff = u.Config.MaxBackoff
+    }
+    u.metrics.NextAttempt = time.Now().Add(backoff)
+    u.logger.Printf("Upkeep failed (%d in a row), retrying in %s: %v", u.metrics.ConsecutiveFailures, backoff, err)
+}
+
+// record measures how late each phase transition in receipt fired
+func (u *Runner) record(ctx context.Context, receipt *types.Receipt) {
+    header, err := u.Config.Client.HeaderByNumber(ctx, receipt.BlockNumber)
+    if err != nil {
+        u.logger.Printf("Failed to fetch block %d: %v", receipt.BlockNumber.Uint64(), err)
+    }
+
+    var transitions []Transition
+    for _, vLog := range receipt.Logs {
+        if vLog == nil || len(vLog.Topics) == 0 {
+            continue
+        }
+        transition, ok := u.transition(ctx, *vLog)
+        if !ok {
+            continue
+        }
+        transition.BlockNumber = receipt.BlockNumber.Uint64()
+        transition.TxHash = receipt.TxHash.Hex()
+        transition.ObservedAt = time.Now()
+        if header != nil {
+            transition.FiredAt = header.Time
+            transition.LateSeconds = int64(header.Time) - int64(transition.Target)
+        }
+        transitions = append(transitions, transition)
+    }
+
+    u.mu.Lock()
+    defer u.mu.Unlock()
+
+    u.metrics.Performed++
+    u.metrics.ConsecutiveFailures = 0
+    u.metrics.LastError = ""
+    u.metrics.NextAttempt = time.Time{}
+    for _, transition := range transitions {
+        if transition.Phase == 1 {
+            u.metrics.PhaseOne.observe(transition.LateSeconds)
+        } else {
+            u.metrics.PhaseTwo.observe(transition.LateSeconds)
+        }
+        u.logger.Printf("Phase %d for %s/%s fired %ds after its target", transition.Phase,
+            transition.Owner, transition.DataName, transition.LateSeconds)
+    }
+    u.metrics.Recent = append(u.metrics.Recent, transitions...)
+    if len(u.metrics.Recent) > maxRecent {
+        u.metrics.Recent = u.metrics.Recent[len(u.metrics.Recent)-maxRecent:]
+    }
+}
+
+// transition decodes a phase change from a performUpkeep log. Phase 1 is
+// due at releaseTime-43200 and phase 2 at releaseTime; KeyReleased does
+// not carry the release time, so it is read back from the record.
+func (u *Runner) transition(ctx context.Context, vLog types.Log) (Transition, bool) {
+    switch vLog.Topics[0] {
+    case bindings.EventID("ReleaseEncryptedData"):
+        event, err := u.Config.Contract.ParseReleaseEncryptedData(vLog)
+        if err != nil {
+            return Transition{}, false
+        }
+        // Local chains can start near timestamp 0, where releaseTime-43200
+        // would wrap around
+        var target uint64
+        if releaseTime := event.ReleaseTime.Uint64(); releaseTime > phaseOneLead {
+            target = releaseTime - phaseOneLead
+        }
+        return Transition{
+            Owner:    event.Owner,
+            DataName: event.DataName,
+            Phase:    1,
+            Target:   target,
+        }, true
+
+    case bindings.EventID("KeyReleased"):
+        event, err := u.Config.Contract.ParseKeyReleased(vLog)
+        if err != nil {
+            return Transition{}, false
+        }
+        _, _, _, _, releaseTime, _, err := u.Config.Contract.GetPublicData(&bind.CallOpts{Context: ctx}, event.DataName, event.Owner)
+        if err != nil {
+            u.logger.Printf("Failed to read release time for %s/%s: %v", event.Owner, event.DataName, err)
+            return Transition{}, false
+        }
+        return Transition{
+            Owner:    event.Owner,
+            DataName: event.DataName,
+            Phase:    2,
+            Target:   releaseTime.Uint64(),
+        }, true
+    }
+    return Transition{}, false
+}
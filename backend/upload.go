@@ -0,0 +1,295 @@
+package main
+
+import (
+    "context"
+    "crypto/sha256"
+    "errors"
+    "fmt"
+    "log"
+    "math/big"
+    "strings"
+
+    "web3server/bindings"
+    "web3server/blobstore"
+    h "web3server/helper"
+    "web3server/jobs"
+    "web3server/signer"
+    "web3server/txmgr"
+
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/common/hexutil"
+    "github.com/ethereum/go-ethereum/core/types"
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    uploadModeServer = "server"
+    uploadModeClient = "client"
+
+    releaseModeKey    = "key"
+    releaseModePuzzle = "puzzle"
+
+    storageChain = "chain"
+    storageBlob  = "blob"
+)
+
+// sealedUpload is the ciphertext, hash and optional release key of an upload
+type sealedUpload struct {
+    EncryptedData []byte
+    Hash          []byte
+    PrivateKey    []byte
+    Cipher        string
+    Mode          string
+    Squarings     uint64
+}
+
+// sealOnServer encrypts the plaintext data field with the requested suite.
+// With release=puzzle the data key is also locked in a time-lock puzzle
+// sized so that solving it takes until releaseTime on this machine.
+func sealOnServer(c *gin.Context, releaseTime uint64) (sealedUpload, error) {
+    return sealData(
+        c.PostForm("data"),
+        c.DefaultPostForm("cipher", h.DefaultCipher().Name()),
+        c.DefaultPostForm("release", releaseModeKey),
+        releaseTime,
+    )
+}
+
+// sealData encrypts data with the named suite and release mode
+func sealData(data, cipherName, releaseMode string, releaseTime uint64) (sealedUpload, error) {
+    suite, err := h.CipherByName(cipherName)
+    if err != nil {
+        return sealedUpload{}, err
+    }
+
+    var squarings uint64
+    switch releaseMode {
+    case releaseModeKey:
+    case releaseModePuzzle:
+        squarings, err = puzzleSquarings(releaseTime)
+        if err != nil {
+            return sealedUpload{}, err
+        }
+    default:
+        return sealedUpload{}, fmt.Errorf("Unknown release mode %q", releaseMode)
+    }
+
+    // Encrypt the data first
+    var encryptedData, privKey []byte
+    if squarings > 0 {
+        encryptedData, privKey, err = h.EncryptDataTimeLocked(suite, data, squarings)
+    } else {
+        encryptedData, privKey, err = h.EncryptDataWith(suite, data)
+    }
+    if err != nil {
+        return sealedUpload{}, fmt.Errorf("Failed to encrypt data: %v", err)
+    }
+
+    // Hash the encrypted data and commit to the release key alongside it
+    hash := h.RecordHash(encryptedData, h.KeyCommitment(privKey))
+
+    return sealedUpload{
+        EncryptedData: encryptedData,
+        Hash:          hash,
+        PrivateKey:    privKey,
+        Cipher:        suite.Name(),
+        Mode:          uploadModeServer,
+        Squarings:     squarings,
+    }, nil
+}
+
+// parseClientSealed accepts a ciphertext the caller produced locally. The
+// server never sees the plaintext; it only holds the release key if the
+// caller hands it over for scheduled release.
+func parseClientSealed(c *gin.Context) (sealedUpload, error) {
+    encryptedData, err := hexutil.Decode(c.PostForm("encryptedData"))
+    if err != nil {
+        return sealedUpload{}, fmt.Errorf("Encrypted data must be 0x-prefixed hex: %v", err)
+    }
+    hash, err := hexutil.Decode(c.PostForm("hash"))
+    if err != nil {
+        return sealedUpload{}, fmt.Errorf("Hash must be 0x-prefixed hex: %v", err)
+    }
+
+    if !h.VerifyCiphertextHash(encryptedData, hash) {
+        return sealedUpload{}, errors.New("Hash does not match the sha256 of the encrypted data")
+    }
+
+    suite, err := h.CipherByName(c.PostForm("cipher"))
+    if err != nil {
+        return sealedUpload{}, err
+    }
+    if h.IsEnvelope(encryptedData) {
+        headerSuite, err := h.EnvelopeCipher(encryptedData)
+        if err != nil {
+            return sealedUpload{}, fmt.Errorf("Invalid ciphertext header: %v", err)
+        }
+        if headerSuite.ID() != suite.ID() {
+            return sealedUpload{}, fmt.Errorf("Cipher %s does not match ciphertext header %s", suite.Name(), headerSuite.Name())
+        }
+    }
+
+    var privKey []byte
+    if releaseKey := c.PostForm("releaseKey"); releaseKey != "" {
+        privKey, err = hexutil.Decode(releaseKey)
+        if err != nil {
+            return sealedUpload{}, fmt.Errorf("Release key must be 0x-prefixed hex: %v", err)
+        }
+        if _, commitment := h.SplitRecordHash(hash); commitment != nil && !h.VerifyKeyCommitment(commitment, privKey) {
+            return sealedUpload{}, errors.New("Release key does not match the key commitment in hash")
+        }
+    }
+
+    return sealedUpload{
+        EncryptedData: encryptedData,
+        Hash:          hash,
+        PrivateKey:    privKey,
+        Cipher:        suite.Name(),
+        Mode:          uploadModeClient,
+    }, nil
+}
+
+// onChainPayload returns what goes into the contract's encryptedData field.
+// With storage=blob only a locator is published and the ciphertext is
+// returned as blob, to be written to the local blob store when the
+// transaction is sent; the record hash still commits to the ciphertext.
+func onChainPayload(storage string, encryptedData []byte) (payload, blob []byte, err error) {
+    // A ciphertext that parses as a locator would be resolved on read
+    if blobstore.IsLocator(encryptedData) {
+        return nil, nil, errors.New("Encrypted data must not start with the blob locator prefix")
+    }
+
+    switch storage {
+    case storageChain:
+        return encryptedData, nil, nil
+    case storageBlob:
+        locator := blobstore.Locator{Digest: sha256.Sum256(encryptedData)}
+        locator.URL = strings.TrimSuffix(blobBaseURL, "/") + "/blobs/" + locator.Hex()
+        return locator.Encode(), encryptedData, nil
+    default:
+        return nil, nil, fmt.Errorf("Unknown storage mode %q", storage)
+    }
+}
+
+// submitUpload sends addStoredData with the next account nonce and records
+// the transaction as an upload job. It returns as soon as the node accepts
+// the transaction, so several uploads can be in flight at once.
+func submitUpload(ctx context.Context, fees txmgr.Fees, owner, dataName string, releaseTime uint64, payload, blob, hash []byte, keyEscrowed bool) (jobs.Job, error) {
+    call := uploadCall{
+        Owner:       owner,
+        DataName:    dataName,
+        ReleaseTime: releaseTime,
+        Payload:     payload,
+        Blob:        blob,
+        Hash:        hash,
+        KeyEscrowed: keyEscrowed,
+    }
+    if err := call.estimate(ctx, fees); err != nil {
+        return jobs.Job{}, err
+    }
+    return call.send(ctx, fees)
+}
+
+// uploadCall is one addStoredData transaction. Estimating and sending are
+// separate steps so a batch can estimate all of its calls concurrently and
+// then send them back to back.
+type uploadCall struct {
+    Owner       string
+    DataName    string
+    ReleaseTime uint64
+    Payload     []byte
+    Blob        []byte
+    Hash        []byte
+    KeyEscrowed bool
+    GasLimit    uint64
+}
+
+func (u *uploadCall) transact(opts *bind.TransactOpts) (*types.Transaction, error) {
+    return contract.AddStoredData(opts, u.Payload, u.Owner, u.DataName, new(big.Int).SetUint64(u.ReleaseTime), u.Hash)
+}
+
+// estimate sets the gas limit, which also proves the call would succeed
+func (u *uploadCall) estimate(ctx context.Context, fees txmgr.Fees) error {
+    opts := signer.TransactOpts(ctx, txSigner, chainID)
+    fees.Apply(opts)
+
+    gasLimit, err := bindings.EstimateGas(opts, u.transact)
+    if err != nil {
+        return fmt.Errorf("Failed to estimate gas limit: %v", err)
+    }
+    u.GasLimit = uint64(float64(gasLimit) * 1.1)
+    return nil
+}
+
+// send signs and sends the estimated call and records it as a job
+func (u *uploadCall) send(ctx context.Context, fees txmgr.Fees) (jobs.Job, error) {
+    opts := signer.TransactOpts(ctx, txSigner, chainID)
+    fees.Apply(opts)
+    opts.GasLimit = u.GasLimit
+
+    // The blob must be readable by the time the locator is on chain, so it
+    // is written just before sending and removed again if the send fails
+    discardBlob, err := u.storeBlob()
+    if err != nil {
+        return jobs.Job{}, fmt.Errorf("Failed to store blob: %v", err)
+    }
+
+    // Reserve the nonce only once the call is known to succeed
+    nonce, err := nonces.Next(ctx)
+    if err != nil {
+        discardBlob()
+        return jobs.Job{}, fmt.Errorf("Failed to reserve account nonce: %v", err)
+    }
+    opts.Nonce = new(big.Int).SetUint64(nonce)
+
+    signedTx, err := u.transact(opts)
+    if err != nil {
+        nonces.Failed(ctx, nonce, err)
+        discardBlob()
+        return jobs.Job{}, fmt.Errorf("Failed to send transaction: %v", err)
+    }
+    nonces.Sent(nonce)
+    transactions.Watch(signedTx, "addStoredData")
+
+    job, err := uploads.Submit(signedTx, txSigner.Address(), u.Owner, u.DataName, u.ReleaseTime, u.KeyEscrowed)
+    if err != nil {
+        return jobs.Job{}, fmt.Errorf("Failed to record upload job: %v", err)
+    }
+    return job, nil
+}
+
+// storeBlob writes the call's blob, if any, and returns a func that removes
+// it again. A blob that was already stored belongs to an earlier upload and
+// is left in place.
+func (u *uploadCall) storeBlob() (func(), error) {
+    if u.Blob == nil {
+        return func() {}, nil
+    }
+    digest := sha256.Sum256(u.Blob)
+    if blobs.Has(digest) {
+        return func() {}, nil
+    }
+    if _, err := blobs.Put(u.Blob); err != nil {
+        return nil, err
+    }
+    return func() {
+        if err := blobs.Delete(digest); err != nil {
+            log.Printf("Failed to remove blob %x of unsent upload %s/%s: %v", digest, u.Owner, u.DataName, err)
+        }
+    }, nil
+}
+
+// escrowReleaseKey stores the release key of a submitted upload. If that
+// fails the job is marked as not escrowed, so the scheduler never tries to
+// publish a key it does not hold.
+func escrowReleaseKey(job jobs.Job, key []byte) (jobs.Job, error) {
+    err := storeReleaseKey(job.Owner, job.DataName, key, job.ReleaseTime)
+    if err == nil {
+        return job, nil
+    }
+
+    if updated, updateErr := uploads.Update(job.ID, func(j *jobs.Job) { j.KeyEscrowed = false }); updateErr == nil {
+        job = updated
+    }
+    return job, fmt.Errorf("Submitted %s but failed to store its release key: %v", job.TxHash, err)
+}
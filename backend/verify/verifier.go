@@ -0,0 +1,433 @@
+package verify
+
+import (
+    "context"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "sync"
+    "time"
+
+    "web3server/bindings"
+    h "web3server/helper"
+    ks "web3server/keystore"
+
+    "github.com/ethereum/go-ethereum/accounts/abi/bind"
+    "github.com/ethereum/go-ethereum/event"
+    bolt "go.etcd.io/bbolt"
+)
+
+// Outcome summarises the checks run against a released key
+type Outcome string
+
+const (
+    OutcomeVerified   Outcome = "verified"
+    OutcomeMismatch   Outcome = "mismatch"
+    OutcomeUnverified Outcome = "unverified"
+)
+
+var (
+    resultsBucket = []byte("verifications")
+    metaBucket    = []byte("meta")
+    nextBlockKey  = []byte("nextBlock")
+)
+
+// RecordFetcher loads the on-chain record a key was released for
+type RecordFetcher func(ctx context.Context, dataName, owner string) (h.PublicData, error)
+
+// Backend is the part of a client needed to bound the backfill
+type Backend interface {
+    BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// VerifierConfig holds the contract whose KeyReleased events are checked.
+// Events from StartBlock on are backfilled in ChunkSize ranges before
+// following new ones.
+type VerifierConfig struct {
+    Client      Backend
+    Contract    *bindings.TwoPhaseCommit
+    FetchRecord RecordFetcher
+    Path        string
+    StartBlock  uint64
+    ChunkSize   uint64
+    RetryDelay  time.Duration
+    MaxBackoff  time.Duration
+}
+
+// Result is the verification of one KeyReleased event
+type Result struct {
+    Owner           string    `json:"owner"`
+    DataName        string    `json:"dataName"`
+    TxHash          string    `json:"transactionHash"`
+    BlockNumber     uint64    `json:"blockNumber"`
+    Outcome         Outcome   `json:"outcome"`
+    HasCommitment   bool      `json:"hasCommitment"`
+    CommitmentValid bool      `json:"commitmentValid"`
+    DecryptionValid bool      `json:"decryptionValid"`
+    Error           string    `json:"error,omitempty"`
+    CheckedAt       time.Time `json:"checkedAt"`
+}
+
+// Verifier checks every published release key against the key commitment
+// and a trial decryption of the record it claims to release. Results and
+// the next block to backfill from are kept on disk, so a restart resumes
+// where the last run stopped.
+type Verifier struct {
+    Config    VerifierConfig
+    db        *bolt.DB
+    results   map[string]Result
+    nextBlock uint64
+    mu        sync.RWMutex
+    wg        sync.WaitGroup
+    logger    *log.Logger
+}
+
+// NewVerifier opens (or creates) the verification store at config.Path
+func NewVerifier(config VerifierConfig) (*Verifier, error) {
+    if config.Client == nil || config.Contract == nil {
+        return nil, errors.New("verifier requires a client and contract")
+    }
+    if config.FetchRecord == nil {
+        return nil, errors.New("verifier requires a record fetcher")
+    }
+    if config.Path == "" {
+        config.Path = "verifications.db"
+    }
+    if config.ChunkSize == 0 {
+        config.ChunkSize = 2000
+    }
+    if config.RetryDelay == 0 {
+        config.RetryDelay = 5 * time.Second
+    }
+    if config.MaxBackoff == 0 {
+        config.MaxBackoff = time.Minute
+    }
+
+    db, err := bolt.Open(config.Path, 0600, &bolt.Options{Timeout: time.Second})
+    if err != nil {
+        return nil, fmt.Errorf("failed to open verification store %s: %w", config.Path, err)
+    }
+
+    results := make(map[string]Result)
+    nextBlock := config.StartBlock
+    err = db.Update(func(tx *bolt.Tx) error {
+        meta, err := tx.CreateBucketIfNotExists(metaBucket)
+        if err != nil {
+            return err
+        }
+        if value := meta.Get(nextBlockKey); len(value) == 8 && binary.BigEndian.Uint64(value) > nextBlock {
+            nextBlock = binary.BigEndian.Uint64(value)
+        }
+
+        bucket, err := tx.CreateBucketIfNotExists(resultsBucket)
+        if err != nil {
+            return err
+        }
+        return bucket.ForEach(func(key, value []byte) error {
+            var result Result
+            if err := json.Unmarshal(value, &result); err != nil {
+                return err
+            }
+            results[string(key)] = result
+            return nil
+        })
+    })
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to load verifications: %w", err)
+    }
+
+    return &Verifier{
+        Config:    config,
+        db:        db,
+        results:   results,
+        nextBlock: nextBlock,
+        logger:    log.New(os.Stdout, "[Verify] ", log.LstdFlags|log.Lmicroseconds),
+    }, nil
+}
+
+func resultKey(owner, dataName string) string {
+    return string(ks.RecordKey(owner, dataName))
+}
+
+// Start subscribes to KeyReleased events until the context is cancelled.
+// Events mined before the subscription, or while it was down, are filled in
+// from the logs starting at the last block seen.
+func (v *Verifier) Start(ctx context.Context) error {
+    events := make(chan *bindings.TwoPhaseCommitKeyReleased)
+    sub, err := v.Config.Contract.WatchKeyReleased(&bind.WatchOpts{Context: ctx}, events)
+    if err != nil {
+        return fmt.Errorf("failed to subscribe to KeyReleased: %w", err)
+    }
+
+    v.wg.Add(1)
+    go func() {
+        defer v.wg.Done()
+        defer func() { sub.Unsubscribe() }()
+
+        v.backfill(ctx)
+        for {
+            select {
+            case event := <-events:
+                v.HandleEvent(ctx, event)
+
+            case err := <-sub.Err():
+                v.logger.Printf("Subscription error: %v", err)
+                sub.Unsubscribe()
+                if sub = v.resubscribe(ctx, events); sub == nil {
+                    return
+                }
+                v.backfill(ctx)
+
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+
+    return nil
+}
+
+// resubscribe retries the subscription with exponential backoff until it
+// succeeds or ctx is cancelled, in which case it returns nil
+func (v *Verifier) resubscribe(ctx context.Context, events chan *bindings.TwoPhaseCommitKeyReleased) event.Subscription {
+    delay := v.Config.RetryDelay
+    for {
+        select {
+        case <-time.After(delay):
+        case <-ctx.Done():
+            return nil
+        }
+
+        sub, err := v.Config.Contract.WatchKeyReleased(&bind.WatchOpts{Context: ctx}, events)
+        if err == nil {
+            v.logger.Printf("Resubscribed to KeyReleased")
+            return sub
+        }
+        v.logger.Printf("Failed to resubscribe, retrying in %s: %v", delay, err)
+        if delay *= 2; delay > v.Config.MaxBackoff {
+            delay = v.Config.MaxBackoff
+        }
+    }
+}
+
+// backfill verifies the KeyReleased events logged from the next unscanned
+// block to the head, retrying until the logs can be read or ctx is cancelled
+func (v *Verifier) backfill(ctx context.Context) {
+    delay := v.Config.RetryDelay
+    for {
+        err := v.filter(ctx)
+        if err == nil {
+            return
+        }
+        v.logger.Printf("Failed to backfill, retrying in %s: %v", delay, err)
+
+        select {
+        case <-time.After(delay):
+        case <-ctx.Done():
+            return
+        }
+        if delay *= 2; delay > v.Config.MaxBackoff {
+            delay = v.Config.MaxBackoff
+        }
+    }
+}
+
+// filter scans the logs up to the head in ChunkSize ranges, saving progress
+// after each one
+func (v *Verifier) filter(ctx context.Context) error {
+    head, err := v.Config.Client.BlockNumber(ctx)
+    if err != nil {
+        return fmt.Errorf("failed to retrieve head block: %w", err)
+    }
+
+    v.mu.RLock()
+    from := v.nextBlock
+    v.mu.RUnlock()
+
+    for from <= head {
+        to := from + v.Config.ChunkSize - 1
+        if to > head {
+            to = head
+        }
+
+        logs, err := v.Config.Contract.FilterKeyReleased(&bind.FilterOpts{Start: from, End: &to, Context: ctx})
+        if err != nil {
+            return fmt.Errorf("failed to filter logs %d-%d: %w", from, to, err)
+        }
+        for logs.Next() {
+            v.HandleEvent(ctx, logs.Event)
+        }
+        err = logs.Error()
+        logs.Close()
+        if err != nil {
+            return fmt.Errorf("failed to read logs %d-%d: %w", from, to, err)
+        }
+
+        v.advance(to + 1)
+        from = to + 1
+    }
+    return nil
+}
+
+// advance moves the next block to backfill from forward and saves it
+func (v *Verifier) advance(block uint64) {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+
+    if block <= v.nextBlock {
+        return
+    }
+    v.nextBlock = block
+    err := v.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(metaBucket).Put(nextBlockKey, binary.BigEndian.AppendUint64(nil, block))
+    })
+    if err != nil {
+        v.logger.Printf("Failed to save backfill progress: %v", err)
+    }
+}
+
+// Wait blocks until the subscription loop has exited
+func (v *Verifier) Wait() {
+    v.wg.Wait()
+}
+
+// Close releases the verification store
+func (v *Verifier) Close() error {
+    return v.db.Close()
+}
+
+// HandleEvent verifies a single KeyReleased event
+func (v *Verifier) HandleEvent(ctx context.Context, event *bindings.TwoPhaseCommitKeyReleased) {
+    // performUpkeep announces phase 2 with an empty key; nothing to check
+    if len(event.PrivateKey) == 0 {
+        return
+    }
+    if event.Raw.Removed {
+        v.Forget(event.Owner, event.DataName, event.Raw.TxHash.Hex())
+        return
+    }
+
+    // Other events of this block may still be missing, so resume from it
+    v.advance(event.Raw.BlockNumber)
+
+    v.mu.RLock()
+    previous, seen := v.results[resultKey(event.Owner, event.DataName)]
+    v.mu.RUnlock()
+    // Backfilled logs can arrive after newer events from the subscription
+    if seen && previous.BlockNumber > event.Raw.BlockNumber {
+        return
+    }
+
+    result := v.Verify(ctx, event.Owner, event.DataName, event.PrivateKey)
+    result.TxHash = event.Raw.TxHash.Hex()
+    result.BlockNumber = event.Raw.BlockNumber
+
+    if result.Outcome == OutcomeMismatch {
+        v.logger.Printf("Released key for %s/%s does not match its record: %s", event.Owner, event.DataName, result.Error)
+    }
+
+    v.mu.Lock()
+    v.results[resultKey(event.Owner, event.DataName)] = result
+    v.save(event.Owner, event.DataName, &result)
+    v.mu.Unlock()
+}
+
+// save writes a result to disk, or deletes it when result is nil; callers
+// hold v.mu. A failed write only costs a re-verification on restart.
+func (v *Verifier) save(owner, dataName string, result *Result) {
+    key := []byte(resultKey(owner, dataName))
+    err := v.db.Update(func(tx *bolt.Tx) error {
+        if result == nil {
+            return tx.Bucket(resultsBucket).Delete(key)
+        }
+        value, err := json.Marshal(result)
+        if err != nil {
+            return err
+        }
+        return tx.Bucket(resultsBucket).Put(key, value)
+    })
+    if err != nil {
+        v.logger.Printf("Failed to save verification for %s/%s: %v", owner, dataName, err)
+    }
+}
+
+// Verify checks key against the commitment and ciphertext stored on chain
+func (v *Verifier) Verify(ctx context.Context, owner, dataName string, key []byte) Result {
+    result := Result{
+        Owner:     owner,
+        DataName:  dataName,
+        CheckedAt: time.Now(),
+    }
+
+    record, err := v.Config.FetchRecord(ctx, dataName, owner)
+    if err != nil {
+        result.Outcome = OutcomeUnverified
+        result.Error = fmt.Sprintf("failed to fetch record: %v", err)
+        return result
+    }
+    if record.ReleaseTime == nil || record.ReleaseTime.Sign() == 0 {
+        result.Outcome = OutcomeMismatch
+        result.Error = "key released for a record that does not exist"
+        return result
+    }
+
+    _, commitment := h.SplitRecordHash(record.Hash)
+    result.HasCommitment = commitment != nil
+    result.CommitmentValid = commitment != nil && h.VerifyKeyCommitment(commitment, key)
+
+    _, err = h.DecryptData(record.EncryptedData, key)
+    result.DecryptionValid = err == nil
+
+    switch {
+    case result.HasCommitment && !result.CommitmentValid:
+        result.Outcome = OutcomeMismatch
+        result.Error = "key does not match the published commitment"
+    case !result.DecryptionValid:
+        result.Outcome = OutcomeMismatch
+        result.Error = fmt.Sprintf("trial decryption failed: %v", err)
+    default:
+        result.Outcome = OutcomeVerified
+    }
+    return result
+}
+
+// Forget drops the verification made for txHash after a reorg removed it
+func (v *Verifier) Forget(owner, dataName, txHash string) {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+
+    key := resultKey(owner, dataName)
+    if result, exists := v.results[key]; exists && result.TxHash == txHash {
+        v.logger.Printf("Dropping verification for %s/%s, %s was reorged out", owner, dataName, txHash)
+        delete(v.results, key)
+        v.save(owner, dataName, nil)
+    }
+}
+
+// Results returns every verification, optionally only the mismatches
+func (v *Verifier) Results(mismatchesOnly bool) []Result {
+    v.mu.RLock()
+    defer v.mu.RUnlock()
+
+    results := make([]Result, 0, len(v.results))
+    for _, result := range v.results {
+        if mismatchesOnly && result.Outcome != OutcomeMismatch {
+            continue
+        }
+        results = append(results, result)
+    }
+    return results
+}
+
+// Get returns the verification for a record
+func (v *Verifier) Get(owner, dataName string) (Result, bool) {
+    v.mu.RLock()
+    defer v.mu.RUnlock()
+
+    result, exists := v.results[resultKey(owner, dataName)]
+    return result, exists
+}